@@ -0,0 +1,123 @@
+package scheduler
+
+/******************************************************************************/
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+/******************************************************************************/
+
+// An EventKind tells what happened to an elapse of a job.
+type EventKind uint8
+
+const (
+	// EventArmed means the scheduler waits for the elapse.
+	EventArmed EventKind = iota
+	// EventFired means a run of the job starts for the elapse.
+	EventFired
+	// EventDone means the run of the elapse returned.
+	EventDone
+	// EventSkipped means the elapse is dropped because the previous run has
+	// not returned, see Skip.
+	EventSkipped
+	// EventMissed means the elapse is not run, because the wall clock jumped
+	// over it or the locker failed.
+	EventMissed
+	// EventCaughtUp means the elapse was missed while the scheduler was not
+	// running and is run now, see Job.Persistent.
+	EventCaughtUp
+)
+
+var eventKindNames = []string{"armed", "fired", "done", "skipped", "missed", "caught up"}
+
+func (k EventKind) String() string {
+	if int(k) < len(eventKindNames) {
+		return eventKindNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", k)
+}
+
+// An Event is something happening to an elapse of a job.
+type Event struct {
+	Kind EventKind
+	Job  string
+	// Expression is the normalized form of the schedule of the job if it is
+	// a fmt.Stringer, such as an Expression, empty otherwise.
+	Expression string
+	// Scheduled is the elapse.
+	Scheduled time.Time
+	// Start is when the run started, for fired and done events.
+	Start time.Time
+	// Duration is how long the run lasted, for done events.
+	Duration time.Duration
+}
+
+// A Hook is told about the events of a scheduler, such as to log them or to
+// trace the runs of the jobs. OnEvent returns the context of the event,
+// which the hook may extend: the one of a fired event is given to the job
+// function and to the done event of the run, such as to carry a span. The
+// contexts of the other events are dropped. OnEvent must be safe for
+// concurrent use and return quickly.
+type Hook interface {
+	OnEvent(ctx context.Context, event Event) context.Context
+}
+
+// MultiHook returns a Hook telling each event to several hooks in turn, the
+// context returned by each one being given to the next. MultiHook() gives a
+// Hook which does nothing.
+func MultiHook(hooks ...Hook) Hook {
+	return multiHook(hooks)
+}
+
+type multiHook []Hook
+
+func (m multiHook) OnEvent(ctx context.Context, event Event) context.Context {
+	for _, hook := range m {
+		ctx = hook.OnEvent(ctx, event)
+	}
+	return ctx
+}
+
+/******************************************************************************/
+
+// A SlogHook is a Hook logging each event as a record. It is the hook of a
+// scheduler unless told otherwise.
+type SlogHook struct {
+	// Logger receives the records, slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+// Levels of the records, by event kind
+var eventLevels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelDebug, slog.LevelWarn, slog.LevelWarn, slog.LevelInfo}
+
+func (h SlogHook) OnEvent(ctx context.Context, event Event) context.Context {
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := slog.LevelInfo
+	if int(event.Kind) < len(eventLevels) {
+		level = eventLevels[event.Kind]
+	}
+	if !logger.Enabled(ctx, level) {
+		return ctx
+	}
+
+	attrs := []slog.Attr{slog.String("job", event.Job)}
+	if event.Expression != "" {
+		attrs = append(attrs, slog.String("expression", event.Expression))
+	}
+	attrs = append(attrs, slog.Time("scheduled", event.Scheduled))
+	if !event.Start.IsZero() {
+		attrs = append(attrs, slog.Time("start", event.Start))
+	}
+	if event.Kind == EventDone {
+		attrs = append(attrs, slog.Duration("duration", event.Duration))
+	}
+	logger.LogAttrs(ctx, level, "scheduler: job "+event.Kind.String(), attrs...)
+	return ctx
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+/******************************************************************************/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/******************************************************************************/
+
+// DefaultRetention is how long the lockers of this package remember an
+// elapse when their Retention is zero.
+const DefaultRetention = 24 * time.Hour
+
+// A Locker elects which of the schedulers sharing it runs a job at an
+// elapse, so that each elapse runs once across replicas. A Locker backed by
+// Redis, etcd or a database can be written outside of this package, it must
+// honor this contract:
+//
+//   - TryLock returns true to a single caller for a given job and elapse,
+//     false to the others, however many call it and at whatever time,
+//   - the elapse is the instant returned by the Next method of the schedule
+//     of the job, the same for all replicas: a key can be made of the job
+//     name and elapse.UnixNano(),
+//   - a lock is never released, it may be forgotten once the elapse is
+//     older than any replica may still be handling,
+//   - an error means the lock could not be decided: the scheduler then
+//     skips the elapse and reports the error,
+//   - it is safe for concurrent use.
+//
+// Replicas must share the schedule of a job: a Jitter with a random delay
+// gives each replica its own elapses.
+type Locker interface {
+	TryLock(ctx context.Context, job string, elapse time.Time) (bool, error)
+}
+
+/******************************************************************************/
+
+// A MemoryLocker is a Locker shared by the schedulers of a process.
+type MemoryLocker struct {
+	// Retention is how long an elapse is remembered, DefaultRetention when
+	// zero.
+	Retention time.Duration
+	mu        sync.Mutex
+	locks     map[string]map[int64]bool // by job, then elapse
+}
+
+func (l *MemoryLocker) TryLock(ctx context.Context, job string, elapse time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.locks == nil {
+		l.locks = make(map[string]map[int64]bool)
+	}
+	elapses := l.locks[job]
+	if elapses == nil {
+		elapses = make(map[int64]bool)
+		l.locks[job] = elapses
+	}
+
+	key := elapse.UnixNano()
+	if elapses[key] {
+		return false, nil
+	}
+	elapses[key] = true
+	forgotten := elapse.Add(-retention(l.Retention)).UnixNano()
+	for old := range elapses {
+		if old < forgotten {
+			delete(elapses, old)
+		}
+	}
+	return true, nil
+}
+
+/******************************************************************************/
+
+// A FileLocker is a Locker shared through a directory, such as one on a
+// network file system, in which it creates a `<job>@<elapse>.lock` file per
+// elapse.
+type FileLocker struct {
+	Dir string
+	// Retention is how long the file of an elapse is kept,
+	// DefaultRetention when zero.
+	Retention time.Duration
+}
+
+func (l FileLocker) TryLock(ctx context.Context, job string, elapse time.Time) (bool, error) {
+	if job == "" || filepath.Base(job) != job || strings.Contains(job, "@") {
+		return false, fmt.Errorf("lock: invalid job name '%s'", job)
+	}
+	path := filepath.Join(l.Dir, fmt.Sprintf("%s@%d.lock", job, elapse.UnixNano()))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	l.forget(job, elapse.Add(-retention(l.Retention)))
+	return true, nil
+}
+
+// forget removes the lock files of a job older than an instant. Failures
+// only leave files behind.
+func (l FileLocker) forget(job string, before time.Time) {
+	paths, _ := filepath.Glob(filepath.Join(l.Dir, job+"@*.lock"))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".lock")
+		nsec, err := strconv.ParseInt(name[strings.LastIndexByte(name, '@')+1:], 10, 64)
+		if err == nil && nsec < before.UnixNano() {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+func retention(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultRetention
+	}
+	return d
+}
@@ -0,0 +1,318 @@
+// Package scheduler runs jobs in process at the elapses of their schedules,
+// such as systemd calendar expressions.
+package scheduler
+
+/******************************************************************************/
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+)
+
+/******************************************************************************/
+
+// A Schedule returns the first elapse following a time instant, or the zero
+// time if there is none. *systemdexpr.Expression is a Schedule.
+type Schedule = systemdexpr.Schedule
+
+// An OverlapPolicy tells what happens when a job elapses while a previous
+// run of it has not returned yet.
+type OverlapPolicy uint8
+
+const (
+	// Skip drops the elapse.
+	Skip OverlapPolicy = iota
+	// Queue runs the job again once the previous run returns, as many times
+	// as it elapsed meanwhile.
+	Queue
+	// Concurrent runs the job alongside the previous run.
+	Concurrent
+)
+
+// A Job is a function run at each elapse of a schedule. The context given to
+// the function is canceled when the scheduler stops, tells which elapse is
+// run, see Elapse, and carries what the hook added to it, see Hook.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Func     func(ctx context.Context)
+	Overlap  OverlapPolicy
+	// Persistent runs the job as soon as the scheduler starts if it elapsed
+	// since its last trigger, like `Persistent=true` does for systemd timers.
+	// The scheduler needs a state store.
+	Persistent bool
+}
+
+/******************************************************************************/
+
+// Options controls a Scheduler. The zero value gives sensible defaults.
+type Options struct {
+	// MaxWait bounds each wait for an elapse: a change of the wall clock is
+	// noticed within it. Defaults to systemdexpr.DefaultMaxWait.
+	MaxWait time.Duration
+	// Clock tells the time, systemdexpr.RealClock when nil.
+	Clock systemdexpr.Clock
+	// Store records the last trigger of each job, if not nil.
+	Store systemdexpr.StateStore
+	// Locker elects the replica which runs each elapse, if not nil.
+	Locker Locker
+	// Observer is told about the elapses of the jobs, if not nil.
+	Observer systemdexpr.Observer
+	// Hook is told about the events of the jobs, a SlogHook when nil.
+	Hook Hook
+	// ErrorHandler is called with the errors of the state store and of the
+	// locker, if not nil.
+	ErrorHandler func(job string, err error)
+}
+
+// A Scheduler runs jobs from the moment Run is called until its context is
+// canceled.
+type Scheduler struct {
+	options Options
+	mu      sync.Mutex
+	jobs    map[string]*jobState
+	ctx     context.Context // while running
+	wg      sync.WaitGroup
+}
+
+type jobState struct {
+	Job
+	expression string // of the events
+	mu         sync.Mutex
+	running    int
+	queued     []time.Time // elapses
+}
+
+// New returns a scheduler without jobs.
+func New(options Options) *Scheduler {
+	if options.MaxWait <= 0 {
+		options.MaxWait = systemdexpr.DefaultMaxWait
+	}
+	if options.Clock == nil {
+		options.Clock = systemdexpr.RealClock
+	}
+	if options.Observer == nil {
+		options.Observer = nopObserver{}
+	}
+	if options.Hook == nil {
+		options.Hook = SlogHook{}
+	}
+	return &Scheduler{options: options, jobs: make(map[string]*jobState)}
+}
+
+/******************************************************************************/
+
+// Add registers a job, which is started right away if the scheduler is
+// running. Job names must be unique.
+func (s *Scheduler) Add(job Job) error {
+	switch {
+	case job.Name == "":
+		return fmt.Errorf("scheduler: job has no name")
+	case job.Schedule == nil:
+		return fmt.Errorf("scheduler: job '%s' has no schedule", job.Name)
+	case job.Func == nil:
+		return fmt.Errorf("scheduler: job '%s' has no function", job.Name)
+	case job.Overlap > Concurrent:
+		return fmt.Errorf("scheduler: job '%s' has an invalid overlap policy", job.Name)
+	case job.Persistent && s.options.Store == nil:
+		return fmt.Errorf("scheduler: job '%s' is persistent but there is no state store", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("scheduler: duplicate job '%s'", job.Name)
+	}
+	j := &jobState{Job: job}
+	if stringer, ok := job.Schedule.(fmt.Stringer); ok {
+		j.expression = stringer.String()
+	}
+	s.jobs[job.Name] = j
+	if s.ctx != nil {
+		s.wg.Add(1)
+		go s.loop(s.ctx, j)
+	}
+	return nil
+}
+
+// Run runs the jobs until the context is canceled, then waits for the runs
+// in progress to return.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("scheduler: already running")
+	}
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+/******************************************************************************/
+
+// loop waits for each elapse of a job in turn and dispatches it.
+func (s *Scheduler) loop(ctx context.Context, j *jobState) {
+	defer s.wg.Done()
+	now := s.options.Clock.Now()
+	if j.Persistent {
+		last, err := s.options.Store.LastTrigger(j.Name)
+		if err != nil {
+			s.reportError(j, err)
+		} else if missed := systemdexpr.MissedElapse(j.Schedule, last, now); !missed.IsZero() {
+			s.event(ctx, j, Event{Kind: EventCaughtUp, Scheduled: missed})
+			s.dispatch(ctx, j, missed)
+		}
+	}
+
+	waiter := systemdexpr.Waiter{Clock: s.options.Clock, MaxWait: s.options.MaxWait}
+	next := j.Schedule.Next(now)
+	for {
+		s.options.Observer.Scheduled(j.Name, next)
+		if next.IsZero() {
+			return
+		}
+		s.event(ctx, j, Event{Kind: EventArmed, Scheduled: next})
+		switch waiter.Wait(ctx, next) {
+		case systemdexpr.WaitCanceled:
+			return
+		case systemdexpr.WaitJumped:
+			// elapses may have moved relative to now
+			next = j.Schedule.Next(s.options.Clock.Now())
+			continue
+		}
+
+		// after a forward jump of the clock, only the latest elapse due is
+		// run, the earlier ones are missed
+		now = s.options.Clock.Now()
+		for later := j.Schedule.Next(next); !later.IsZero() && !later.After(now); later = j.Schedule.Next(later) {
+			s.options.Observer.Missed(j.Name, next)
+			s.event(ctx, j, Event{Kind: EventMissed, Scheduled: next})
+			next = later
+		}
+		s.dispatch(ctx, j, next)
+		next = j.Schedule.Next(next)
+	}
+}
+
+// dispatch starts a run of a job for an elapse, unless its overlap policy
+// says otherwise or another replica runs it.
+func (s *Scheduler) dispatch(ctx context.Context, j *jobState, elapse time.Time) {
+	if s.options.Locker != nil {
+		// an elapse skipped here may still be run by another replica
+		j.mu.Lock()
+		skipped := j.running > 0 && j.Overlap == Skip
+		j.mu.Unlock()
+		if skipped {
+			s.options.Observer.Missed(j.Name, elapse)
+			s.event(ctx, j, Event{Kind: EventSkipped, Scheduled: elapse})
+			return
+		}
+		locked, err := s.options.Locker.TryLock(ctx, j.Name, elapse)
+		if err != nil {
+			s.reportError(j, err)
+			s.options.Observer.Missed(j.Name, elapse)
+			s.event(ctx, j, Event{Kind: EventMissed, Scheduled: elapse})
+			return
+		}
+		if !locked {
+			return
+		}
+	}
+
+	j.mu.Lock()
+	if j.running > 0 {
+		switch j.Overlap {
+		case Skip:
+			j.mu.Unlock()
+			s.options.Observer.Missed(j.Name, elapse)
+			s.event(ctx, j, Event{Kind: EventSkipped, Scheduled: elapse})
+			return
+		case Queue:
+			j.queued = append(j.queued, elapse)
+			j.mu.Unlock()
+			return
+		}
+	}
+	j.running++
+	j.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx, j, elapse)
+}
+
+// trigger records that a job is triggered, when a run starts: a queued
+// elapse dropped on shutdown is then caught up by a persistent job.
+func (s *Scheduler) trigger(j *jobState) {
+	if s.options.Store == nil {
+		return
+	}
+	if err := s.options.Store.SetLastTrigger(j.Name, s.options.Clock.Now()); err != nil {
+		s.reportError(j, err)
+	}
+}
+
+// event tells the hook about an event of a job and returns its context.
+func (s *Scheduler) event(ctx context.Context, j *jobState, event Event) context.Context {
+	event.Job = j.Name
+	event.Expression = j.expression
+	return s.options.Hook.OnEvent(ctx, event)
+}
+
+func (s *Scheduler) reportError(j *jobState, err error) {
+	if s.options.ErrorHandler != nil {
+		s.options.ErrorHandler(j.Name, err)
+	}
+}
+
+// run runs a job, then the runs queued meanwhile.
+func (s *Scheduler) run(ctx context.Context, j *jobState, elapse time.Time) {
+	defer s.wg.Done()
+	for {
+		s.trigger(j)
+		start := s.options.Clock.Now()
+		s.options.Observer.Started(j.Name, elapse, start)
+		runCtx := s.event(ctx, j, Event{Kind: EventFired, Scheduled: elapse, Start: start})
+		j.Func(context.WithValue(runCtx, elapseKey{}, elapse))
+		s.event(runCtx, j, Event{Kind: EventDone, Scheduled: elapse, Start: start, Duration: s.options.Clock.Now().Sub(start)})
+
+		j.mu.Lock()
+		if len(j.queued) == 0 || ctx.Err() != nil {
+			j.queued = nil
+			j.running--
+			j.mu.Unlock()
+			return
+		}
+		elapse = j.queued[0]
+		j.queued = j.queued[1:]
+		j.mu.Unlock()
+	}
+}
+
+type elapseKey struct{}
+
+// Elapse returns the elapse run by a job, given the context of the job, or
+// the zero time for another context.
+func Elapse(ctx context.Context) time.Time {
+	elapse, _ := ctx.Value(elapseKey{}).(time.Time)
+	return elapse
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Scheduled(string, time.Time)          {}
+func (nopObserver) Started(string, time.Time, time.Time) {}
+func (nopObserver) Missed(string, time.Time)             {}
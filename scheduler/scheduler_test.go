@@ -0,0 +1,604 @@
+package scheduler
+
+/******************************************************************************/
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************************************************/
+
+var (
+	_ Schedule = (*systemdexpr.Expression)(nil)
+	_ Schedule = (*systemdexpr.Jitter)(nil)
+)
+
+// every elapses at each multiple of a duration.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(e)).Add(time.Duration(e))
+}
+
+// once elapses at a single instant.
+type once time.Time
+
+func (o once) Next(t time.Time) time.Time {
+	if t.Before(time.Time(o)) {
+		return time.Time(o)
+	}
+	return time.Time{}
+}
+
+// burst elapses as a schedule does until an instant.
+type burst struct {
+	Schedule
+	until time.Time
+}
+
+func (b burst) Next(t time.Time) time.Time {
+	if next := b.Schedule.Next(t); next.Before(b.until) {
+		return next
+	}
+	return time.Time{}
+}
+
+// recorder is an Observer recording the elapses it is told, formatted as
+// times of the day.
+type recorder struct {
+	mu                         sync.Mutex
+	scheduled, started, missed []string
+}
+
+func (r *recorder) record(list *[]string, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*list = append(*list, t.Format("15:04"))
+}
+
+func (r *recorder) Scheduled(timer string, next time.Time) { r.record(&r.scheduled, next) }
+func (r *recorder) Started(timer string, elapse, start time.Time) {
+	r.record(&r.started, elapse)
+}
+func (r *recorder) Missed(timer string, elapse time.Time) { r.record(&r.missed, elapse) }
+
+// spanKey is the context key of the span of tracingHook.
+type spanKey struct{}
+
+// tracingHook is a Hook recording the events it is told, adding a span to
+// the context of fired events.
+type tracingHook struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *tracingHook) OnEvent(ctx context.Context, event Event) context.Context {
+	if event.Kind == EventFired {
+		ctx = context.WithValue(ctx, spanKey{}, event.Scheduled.Format("span 15:04"))
+	}
+	record := fmt.Sprintf("%s %s %s", event.Kind, event.Expression, event.Scheduled.Format("15:04"))
+	if !event.Start.IsZero() {
+		record += event.Start.Format(" 15:04:05")
+	}
+	if span, ok := ctx.Value(spanKey{}).(string); ok {
+		record += ", " + span
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, record)
+	return ctx
+}
+
+// runFor runs a scheduler for a while and returns once it stopped.
+func runFor(t *testing.T, s *Scheduler, d time.Duration) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	require.NoError(t, s.Run(ctx))
+}
+
+// waitIdle returns once no run of a job is in progress.
+func waitIdle(t *testing.T, s *Scheduler, name string) {
+	t.Helper()
+	s.mu.Lock()
+	j := s.jobs[name]
+	s.mu.Unlock()
+	require.Eventually(t, func() bool {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return j.running == 0
+	}, time.Second, time.Millisecond)
+}
+
+/******************************************************************************/
+
+func TestAdd(t *testing.T) {
+	s := New(Options{Hook: MultiHook()})
+	f := func(context.Context) {}
+	require.NoError(t, s.Add(Job{Name: "a", Schedule: every(time.Second), Func: f}))
+	assert.EqualError(t, s.Add(Job{Name: "a", Schedule: every(time.Second), Func: f}), "scheduler: duplicate job 'a'")
+	assert.EqualError(t, s.Add(Job{Schedule: every(time.Second), Func: f}), "scheduler: job has no name")
+	assert.EqualError(t, s.Add(Job{Name: "b", Func: f}), "scheduler: job 'b' has no schedule")
+	assert.EqualError(t, s.Add(Job{Name: "b", Schedule: every(time.Second)}), "scheduler: job 'b' has no function")
+	assert.Error(t, s.Add(Job{Name: "b", Schedule: every(time.Second), Func: f, Overlap: 7}))
+}
+
+func TestRun(t *testing.T) {
+	s := New(Options{Hook: MultiHook()})
+	var runs int32
+	require.NoError(t, s.Add(Job{
+		Name:     "tick",
+		Schedule: every(20 * time.Millisecond),
+		Func:     func(context.Context) { atomic.AddInt32(&runs, 1) },
+	}))
+	runFor(t, s, 210*time.Millisecond)
+	assert.InDelta(t, 10, atomic.LoadInt32(&runs), 2)
+
+	// the jobs stay registered and the scheduler can run again
+	runFor(t, s, 50*time.Millisecond)
+	assert.Greater(t, atomic.LoadInt32(&runs), int32(10))
+}
+
+func TestRunStops(t *testing.T) {
+	s := New(Options{Hook: MultiHook()})
+	started := make(chan struct{})
+	var canceled int32
+	require.NoError(t, s.Add(Job{
+		Name:     "slow",
+		Schedule: once(time.Now().Add(10 * time.Millisecond)),
+		Func: func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			atomic.StoreInt32(&canceled, 1)
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	<-started
+	assert.EqualError(t, s.Run(ctx), "scheduler: already running")
+	cancel()
+	require.NoError(t, <-done)
+	// Run waited for the job to return
+	assert.Equal(t, int32(1), atomic.LoadInt32(&canceled))
+}
+
+func TestAddWhileRunning(t *testing.T) {
+	s := New(Options{Hook: MultiHook()})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	ran := make(chan struct{})
+	require.NoError(t, s.Add(Job{
+		Name:     "late",
+		Schedule: once(time.Now().Add(10 * time.Millisecond)),
+		Func:     func(context.Context) { close(ran) },
+	}))
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Error("job added while running did not run")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestOverlap(t *testing.T) {
+	cases := []struct {
+		policy        OverlapPolicy
+		minRuns       int32
+		maxRuns       int32
+		maxConcurrent int32
+	}{
+		// a 60ms job elapsing 5 times, each 20ms
+		{Skip, 2, 3, 1},
+		{Queue, 5, 5, 1},
+		{Concurrent, 5, 5, 4},
+	}
+	for _, c := range cases {
+		var runs, current, maxConcurrent int32
+		var mu sync.Mutex
+		start := time.Now().Truncate(20 * time.Millisecond)
+		s := New(Options{Hook: MultiHook()})
+		require.NoError(t, s.Add(Job{
+			Name:     "slow",
+			Schedule: burst{every(20 * time.Millisecond), start.Add(110 * time.Millisecond)},
+			Overlap:  c.policy,
+			Func: func(ctx context.Context) {
+				atomic.AddInt32(&runs, 1)
+				n := atomic.AddInt32(&current, 1)
+				mu.Lock()
+				if n > maxConcurrent {
+					maxConcurrent = n
+				}
+				mu.Unlock()
+				time.Sleep(60 * time.Millisecond)
+				atomic.AddInt32(&current, -1)
+			},
+		}))
+		runFor(t, s, 500*time.Millisecond)
+		assert.GreaterOrEqual(t, runs, c.minRuns, "policy %d", c.policy)
+		assert.LessOrEqual(t, runs, c.maxRuns, "policy %d", c.policy)
+		assert.LessOrEqual(t, maxConcurrent, c.maxConcurrent, "policy %d", c.policy)
+		if c.policy == Concurrent {
+			assert.Greater(t, maxConcurrent, int32(1))
+		}
+	}
+}
+
+func TestRunFakeClock(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 23, 59, 0, 0, time.UTC)
+	clock := systemdexpr.NewFakeClock(start)
+	s := New(Options{Clock: clock, Hook: MultiHook()})
+	runs := make(chan time.Time)
+	require.NoError(t, s.Add(Job{
+		Name:     "daily",
+		Schedule: systemdexpr.MustParse("daily"),
+		Func:     func(ctx context.Context) { runs <- Elapse(ctx) },
+	}))
+	assert.True(t, Elapse(context.Background()).IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), <-runs)
+	// the next elapse would be skipped if the run had not returned yet
+	waitIdle(t, s, "daily")
+
+	// waits are chunked, but a single advance crosses them all
+	clock.BlockUntil(1)
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, start.Add(24*time.Hour+time.Minute), <-runs)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestRunClockJump(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := systemdexpr.NewFakeClock(start)
+	s := New(Options{Clock: clock, Hook: MultiHook()})
+	runs := make(chan time.Time, 1)
+	require.NoError(t, s.Add(Job{
+		Name:     "hourly",
+		Schedule: systemdexpr.MustParse("hourly"),
+		Func:     func(ctx context.Context) { runs <- Elapse(ctx) },
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// the wall clock is set back by 2h while waiting for 13:00
+	clock.BlockUntil(1)
+	clock.Jump(-2 * time.Hour)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	assert.Empty(t, runs)
+	clock.Advance(59 * time.Minute)
+	assert.Equal(t, start.Add(-time.Hour), <-runs)
+	waitIdle(t, s, "hourly")
+
+	// the host resumes at 14:30 while waiting for 12:00: 14:00 is run, 12:00
+	// and 13:00 are missed
+	clock.BlockUntil(1)
+	clock.Jump(3*time.Hour + 30*time.Minute)
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(2*time.Hour), <-runs)
+	waitIdle(t, s, "hourly")
+	clock.BlockUntil(1)
+	clock.Advance(29 * time.Minute)
+	assert.Equal(t, start.Add(3*time.Hour), <-runs)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestObserver(t *testing.T) {
+	clock := systemdexpr.NewFakeClock(time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC))
+	observer := &recorder{}
+	s := New(Options{Clock: clock, Observer: observer, Hook: MultiHook()})
+	release := make(chan struct{})
+	require.NoError(t, s.Add(Job{
+		Name:     "minutely",
+		Schedule: systemdexpr.MustParse("minutely"),
+		Func:     func(context.Context) { <-release },
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// 12:02 is skipped while the run of 12:01 has not returned
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	release <- struct{}{}
+	waitIdle(t, s, "minutely")
+
+	// 12:03 to 12:05 are jumped over, 12:06 is run
+	clock.Jump(3 * time.Minute)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	release <- struct{}{}
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"12:01", "12:02", "12:03", "12:07"}, observer.scheduled)
+	assert.Equal(t, []string{"12:01", "12:06"}, observer.started)
+	assert.Equal(t, []string{"12:02", "12:03", "12:04", "12:05"}, observer.missed)
+}
+
+func TestHook(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 30, 0, time.UTC)
+	clock := systemdexpr.NewFakeClock(now)
+	store := systemdexpr.NewMemoryStateStore()
+	require.NoError(t, store.SetLastTrigger("minutely", now.Add(-150*time.Second)))
+	hook := &tracingHook{}
+	s := New(Options{Clock: clock, Store: store, Hook: hook})
+	var mu sync.Mutex
+	var spans []string
+	require.NoError(t, s.Add(Job{
+		Name:       "minutely",
+		Schedule:   systemdexpr.MustParse("minutely"),
+		Persistent: true,
+		Func: func(ctx context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			spans = append(spans, ctx.Value(spanKey{}).(string))
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	clock.BlockUntil(1)
+	waitIdle(t, s, "minutely")
+	clock.Advance(30 * time.Second)
+	clock.BlockUntil(1)
+	waitIdle(t, s, "minutely")
+	clock.Jump(2 * time.Minute)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.ElementsMatch(t, []string{
+		"caught up *-*-* *:*:00 11:59",
+		"fired *-*-* *:*:00 11:59 12:00:30, span 11:59",
+		"done *-*-* *:*:00 11:59 12:00:30, span 11:59",
+		"armed *-*-* *:*:00 12:01",
+		"fired *-*-* *:*:00 12:01 12:01:00, span 12:01",
+		"done *-*-* *:*:00 12:01 12:01:00, span 12:01",
+		"armed *-*-* *:*:00 12:02",
+		"missed *-*-* *:*:00 12:02",
+		"missed *-*-* *:*:00 12:03",
+		"fired *-*-* *:*:00 12:04 12:04:00, span 12:04",
+		"done *-*-* *:*:00 12:04 12:04:00, span 12:04",
+		"armed *-*-* *:*:00 12:05",
+	}, hook.events)
+	assert.ElementsMatch(t, []string{"span 11:59", "span 12:01", "span 12:04"}, spans)
+}
+
+func TestSlogHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	scheduled := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	var hook Hook = MultiHook(SlogHook{Logger: logger}, MultiHook())
+
+	assert.Equal(t, ctx, hook.OnEvent(ctx, Event{Kind: EventArmed, Job: "backup", Scheduled: scheduled}))
+	hook.OnEvent(ctx, Event{Kind: EventFired, Job: "backup", Expression: "*-*-* 12:00:00", Scheduled: scheduled, Start: scheduled.Add(time.Second)})
+	hook.OnEvent(ctx, Event{Kind: EventDone, Job: "backup", Scheduled: scheduled, Start: scheduled.Add(time.Second), Duration: 1500 * time.Millisecond})
+	hook.OnEvent(ctx, Event{Kind: EventSkipped, Job: "backup", Scheduled: scheduled})
+	assert.Equal(t, `level=INFO msg="scheduler: job fired" job=backup expression="*-*-* 12:00:00" scheduled=2026-10-18T12:00:00.000Z start=2026-10-18T12:00:01.000Z
+level=WARN msg="scheduler: job skipped" job=backup scheduled=2026-10-18T12:00:00.000Z
+`, buf.String())
+	assert.Equal(t, "caught up", EventCaughtUp.String())
+	assert.Equal(t, "EventKind(9)", EventKind(9).String())
+}
+
+func TestPersistent(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := systemdexpr.NewFakeClock(now)
+	store := systemdexpr.NewMemoryStateStore()
+	require.NoError(t, store.SetLastTrigger("missed", now.AddDate(0, 0, -2)))
+	require.NoError(t, store.SetLastTrigger("on-time", now.Add(-time.Hour)))
+	require.NoError(t, store.SetLastTrigger("not-persistent", now.AddDate(0, 0, -2)))
+
+	s := New(Options{Clock: clock, Store: store, Hook: MultiHook()})
+	runs := make(chan string, 3)
+	for _, name := range []string{"missed", "on-time", "not-persistent"} {
+		name := name
+		require.NoError(t, s.Add(Job{
+			Name:       name,
+			Schedule:   systemdexpr.MustParse("daily"),
+			Func:       func(context.Context) { runs <- name },
+			Persistent: name != "not-persistent",
+		}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	assert.Equal(t, "missed", <-runs)
+	clock.BlockUntil(3)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, runs)
+
+	last, err := store.LastTrigger("missed")
+	require.NoError(t, err)
+	assert.Equal(t, now, last)
+	last, err = store.LastTrigger("on-time")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), last)
+
+	err = New(Options{}).Add(Job{Name: "a", Schedule: every(time.Second), Func: func(context.Context) {}, Persistent: true})
+	assert.EqualError(t, err, "scheduler: job 'a' is persistent but there is no state store")
+}
+
+func TestPersistentQueue(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 30, 0, time.UTC)
+	clock := systemdexpr.NewFakeClock(start)
+	store := systemdexpr.NewMemoryStateStore()
+	s := New(Options{Clock: clock, Store: store, Hook: MultiHook()})
+	started := make(chan time.Time, 2)
+	release := make(chan struct{})
+	require.NoError(t, s.Add(Job{
+		Name:     "slow",
+		Schedule: every(time.Minute),
+		Overlap:  Queue,
+		Func: func(ctx context.Context) {
+			started <- Elapse(ctx)
+			<-release
+		},
+		Persistent: true,
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), <-started)
+
+	// the elapse of 12:02 is queued behind the run of 12:01, then dropped
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	cancel()
+	close(release)
+	require.NoError(t, <-done)
+	assert.Empty(t, started)
+
+	last, err := store.LastTrigger("slow")
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(30*time.Second), last)
+}
+
+func TestPersistentStoreErrors(t *testing.T) {
+	var mu sync.Mutex
+	var errs []string
+	store := systemdexpr.FileStateStore{Dir: "/nonexistent"}
+	clock := systemdexpr.NewFakeClock(time.Date(2026, time.October, 18, 23, 59, 0, 0, time.UTC))
+	s := New(Options{Clock: clock, Store: store, Hook: MultiHook(), ErrorHandler: func(job string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, job)
+	}})
+	ran := make(chan struct{})
+	require.NoError(t, s.Add(Job{Name: "daily", Schedule: systemdexpr.MustParse("daily"), Func: func(context.Context) { close(ran) }}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-ran
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"daily"}, errs)
+}
+
+/******************************************************************************/
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	elapse := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	for _, locker := range []Locker{&MemoryLocker{Retention: time.Hour}, FileLocker{Dir: dir, Retention: time.Hour}} {
+		for i, expected := range []bool{true, false, false} {
+			locked, err := locker.TryLock(ctx, "backup", elapse)
+			require.NoError(t, err)
+			assert.Equal(t, expected, locked, "%T, attempt %d", locker, i)
+		}
+		locked, err := locker.TryLock(ctx, "cleanup", elapse)
+		require.NoError(t, err)
+		assert.True(t, locked, "%T", locker)
+
+		// once forgotten, an elapse could be locked again
+		locked, err = locker.TryLock(ctx, "backup", elapse.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.True(t, locked, "%T", locker)
+		locked, err = locker.TryLock(ctx, "backup", elapse)
+		require.NoError(t, err)
+		assert.True(t, locked, "%T", locker)
+	}
+
+	_, err := FileLocker{Dir: dir}.TryLock(ctx, "../backup", elapse)
+	assert.Error(t, err)
+	_, err = FileLocker{Dir: filepath.Join(dir, "missing")}.TryLock(ctx, "backup", elapse)
+	assert.Error(t, err)
+}
+
+func TestLockerReplicas(t *testing.T) {
+	const replicas = 4
+	const elapses = 5
+	for _, locker := range []Locker{&MemoryLocker{}, FileLocker{Dir: t.TempDir()}} {
+		start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+		clock := systemdexpr.NewFakeClock(start)
+		expr := systemdexpr.MustParse("minutely")
+
+		var mu sync.Mutex
+		runs := make(map[time.Time][]int)
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < replicas; i++ {
+			i := i
+			s := New(Options{Clock: clock, Locker: locker, Hook: MultiHook()})
+			require.NoError(t, s.Add(Job{Name: "report", Schedule: expr, Func: func(ctx context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				runs[Elapse(ctx)] = append(runs[Elapse(ctx)], i)
+			}}))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, s.Run(ctx))
+			}()
+		}
+
+		for i := 0; i < elapses; i++ {
+			clock.BlockUntil(replicas)
+			clock.Advance(time.Minute)
+		}
+		// every replica is past the last elapse once waiting for the next one
+		clock.BlockUntil(replicas)
+		cancel()
+		wg.Wait()
+
+		assert.Len(t, runs, elapses, "%T", locker)
+		for elapse, replicas := range runs {
+			assert.Len(t, replicas, 1, "%T: elapse %s run by replicas %v", locker, elapse, replicas)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package systemdexpr
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -21,15 +22,18 @@ type Expression struct {
 	workdaysOfMonth        map[int]bool
 	lastDayOfMonth         bool
 	lastWorkdayOfMonth     bool
+	daysFromEndOfMonth     map[int]bool
 	daysOfMonthRestricted  bool
-	actualDaysOfMonthList  []int
 	monthList              []int
 	daysOfWeek             map[int]bool
 	specificWeekDaysOfWeek map[int]bool
 	lastWeekDaysOfWeek     map[int]bool
 	daysOfWeekRestricted   bool
+	bothDaysRequired       bool
 	yearList               []int
 	timeZone               *time.Location
+	// directives of each field as written, by field name, see String
+	directives map[string][]listSegment
 }
 
 /******************************************************************************/
@@ -49,35 +53,103 @@ func MustParse(cronLine string) *Expression {
 
 /******************************************************************************/
 
+// TwoDigitYearMode tells the parser how to expand a two-digit year such as
+// `12-10-15` into a full year.
+type TwoDigitYearMode uint8
+
+const (
+	// TwoDigitYear2000 maps `00`..`99` to 2000..2099.
+	TwoDigitYear2000 TwoDigitYearMode = 0
+	// TwoDigitYear1970 maps `70`..`99` to 1970..1999 and `00`..`69` to
+	// 2000..2069, as systemd does.
+	TwoDigitYear1970 TwoDigitYearMode = 1
+)
+
+// Options controls how ParseWithOptions reads an expression. The zero value
+// gives the same behavior as Parse.
+type Options struct {
+	// Location is used when the expression does not name a time zone. When
+	// nil, Next evaluates the expression in the location of its argument.
+	Location *time.Location
+	// Strict rejects the cron-ish extras which systemd does not know about:
+	// `L`, `W` and `#` directives and `-` ranges, as well as time zones
+	// missing from the time zone database.
+	Strict bool
+	// TwoDigitYears selects how two-digit years are expanded.
+	TwoDigitYears TwoDigitYearMode
+	// NoAliases rejects the built-in aliases such as `hourly` or `weekly`.
+	NoAliases bool
+	// Locale, when it is also a NameParser, lets weekday and month names be
+	// given in its language.
+	Locale Locale
+	// MatchBothDays makes an expression restricting both the weekday and the
+	// day of the month, such as `Mon *-*-01..07`, elapse on the days matching
+	// both of them, as systemd does, instead of either of them.
+	MatchBothDays bool
+}
+
+/******************************************************************************/
+
 // Parse returns a new Expression pointer. An error is returned if a malformed
 // cron expression is supplied.
+//
+// A trailing time zone is looked up in the time zone database, such as
+// `Europe/Berlin`. A name missing from it, such as `CET` on some systems, is
+// read as a zone of that name at UTC+0; ParseWithOptions rejects it when
+// Strict.
+//
+// An expression restricting both the weekday and the day of the month elapses
+// on the days matching either of them, see Options.MatchBothDays for the rule
+// of systemd.
 // See <https://github.com/gorhill/cronexpr#implementation> for documentation
 // about what is a well-formed cron expression from this library's point of
 // view.
-
 func Parse(systemdLine string) (*Expression, error) {
+	return ParseWithOptions(systemdLine, Options{})
+}
+
+/******************************************************************************/
+
+// ParseWithOptions is like Parse, but lets the caller choose the default time
+// zone, how strictly systemd syntax is enforced, how two-digit years are
+// expanded, whether aliases are allowed and the language of names.
+func ParseWithOptions(systemdLine string, options Options) (*Expression, error) {
 	var expr = Expression{
-		expression: systemdLine,
+		expression:       systemdLine,
+		bothDaysRequired: options.MatchBothDays,
+	}
+	original, err := expr.normalyzeSystemd(options)
+	if err != nil {
+		return nil, newParseError(fmt.Errorf("invalid expression, %s", err), systemdLine, 0, len(systemdLine))
 	}
-	if err := expr.normalyzeSystemd(); err != nil {
-		return nil, fmt.Errorf("invalid expression, %s", err)
+	// errors point into the expression as written, unless lowering it moved
+	// its bytes
+	source := original
+	if len(original) != len(expr.expression) {
+		source = expr.expression
 	}
 
 	indices := fieldFinder.FindAllStringIndex(expr.expression, -1)
 	fieldCount := len(indices)
 	fieldI := 0
-	var err error
 
 	if fieldCount > 4 {
-		return nil, fmt.Errorf("too much field(s)")
+		end := indices[fieldCount-1][1]
+		return nil, newParseError(fmt.Errorf("too much field(s)"), source, indices[4][0], end-indices[4][0])
 	}
 
 	// Try parse weekday field
 	if expr.validateField(fieldI, WeekDayField, indices) {
 		// parse weekday
-		err = expr.dowFieldHandler(expr.expression[indices[fieldI][0]:indices[fieldI][1]])
+		weekdayString := expr.expression[indices[fieldI][0]:indices[fieldI][1]]
+		if options.Strict {
+			err = checkStrictField(weekdayString, WeekDayField, options)
+		}
+		if err == nil {
+			err = expr.dowFieldHandler(weekdayString)
+		}
 		if err != nil {
-			return nil, err
+			return nil, newParseError(err, source, indices[fieldI][0], len(weekdayString))
 		}
 		fieldI++
 	} else {
@@ -92,19 +164,55 @@ func Parse(systemdLine string) (*Expression, error) {
 		dateString := expr.expression[indices[fieldI][0]:indices[fieldI][1]]
 
 		DateIndices := entryDateFinder.FindAllStringIndex(dateString, -1)
+		if len(DateIndices) == 0 {
+			return nil, newParseError(fmt.Errorf("%s field: missing directive", domDescriptor.name), source, indices[fieldI][0], len(dateString))
+		}
+		dateFields := make([]string, 0, len(DateIndices)+1)
+		dateOffsets := make([]int, 0, len(DateIndices)+1)
+		for _, index := range DateIndices {
+			dateFields = append(dateFields, dateString[index[0]:index[1]])
+			dateOffsets = append(dateOffsets, indices[fieldI][0]+index[0])
+		}
+		// `*-02~03`, days are counted from the end of the month
+		fromEnd := false
+		if i := strings.IndexByte(dateFields[len(dateFields)-1], '~'); i >= 0 {
+			last := dateFields[len(dateFields)-1]
+			dateFields = append(dateFields[:len(dateFields)-1], last[:i], last[i+1:])
+			offset := dateOffsets[len(dateOffsets)-1]
+			dateOffsets = append(dateOffsets, offset+i+1)
+			fromEnd = true
+		}
+		dateError := func(err error) error {
+			i := len(dateFields) - field
+			return newParseError(err, source, dateOffsets[i], len(dateFields[i]))
+		}
+		if options.Strict {
+			if err = checkStrictField(dateFields[len(dateFields)-field], DayField, options); err != nil {
+				return nil, dateError(err)
+			}
+		}
 
 		// day of month field
-		err = expr.domFieldHandler(dateString[DateIndices[len(DateIndices)-field][0]:DateIndices[len(DateIndices)-field][1]])
+		if fromEnd {
+			err = expr.domFromEndFieldHandler(dateFields[len(dateFields)-field])
+		} else {
+			err = expr.domFieldHandler(dateFields[len(dateFields)-field])
+		}
 		if err != nil {
-			return nil, err
+			return nil, dateError(err)
 		}
 		field += 1
 
 		// month field
-		if len(DateIndices)-field >= 0 {
-			err = expr.monthFieldHandler(dateString[DateIndices[len(DateIndices)-field][0]:DateIndices[len(DateIndices)-field][1]])
+		if len(dateFields)-field >= 0 {
+			if options.Strict {
+				err = checkStrictField(dateFields[len(dateFields)-field], MonthField, options)
+			}
+			if err == nil {
+				err = expr.monthFieldHandler(dateFields[len(dateFields)-field])
+			}
 			if err != nil {
-				return nil, err
+				return nil, dateError(err)
 			}
 			field += 1
 		} else {
@@ -112,14 +220,11 @@ func Parse(systemdLine string) (*Expression, error) {
 		}
 
 		// year field
-		if len(DateIndices)-field >= 0 {
-			yearString := dateString[DateIndices[len(DateIndices)-field][0]:DateIndices[len(DateIndices)-field][1]]
-			if len(yearString) == 2 {
-				yearString = "20" + yearString
-			}
+		if len(dateFields)-field >= 0 {
+			yearString := expandTwoDigitYears(dateFields[len(dateFields)-field], options.TwoDigitYears)
 			err = expr.yearFieldHandler(yearString)
 			if err != nil {
-				return nil, err
+				return nil, dateError(err)
 			}
 		} else {
 			expr.yearList = yearDescriptor.defaultList
@@ -137,30 +242,32 @@ func Parse(systemdLine string) (*Expression, error) {
 		field := 0
 		timeString := expr.expression[indices[fieldI][0]:indices[fieldI][1]]
 		TimeIndices := entryTimeFinder.FindAllStringIndex(timeString, -1)
+		timeError := func(err error) error {
+			index := TimeIndices[field]
+			return newParseError(err, source, indices[fieldI][0]+index[0], index[1]-index[0])
+		}
 
 		// hour field
 		err = expr.hourFieldHandler(timeString[TimeIndices[field][0]:TimeIndices[field][1]])
 		if err != nil {
-			return nil, err
+			return nil, timeError(err)
 		}
 		field += 1
 
 		// minute field
 		err = expr.minuteFieldHandler(timeString[TimeIndices[field][0]:TimeIndices[field][1]])
 		if err != nil {
-			return nil, err
+			return nil, timeError(err)
 		}
 		field += 1
 
 		// seconds field
 		if field < len(TimeIndices) {
-			fmt.Errorf(timeString[TimeIndices[field][0]:TimeIndices[field][1]])
 			err = expr.secondFieldHandler(timeString[TimeIndices[field][0]:TimeIndices[field][1]])
 			if err != nil {
-				return nil, err
+				return nil, timeError(err)
 			}
 		} else {
-			fmt.Errorf("default seconds")
 			err = expr.secondFieldHandler("00")
 			if err != nil {
 				return nil, err
@@ -184,10 +291,22 @@ func Parse(systemdLine string) (*Expression, error) {
 		}
 	}
 
+	expr.timeZone = options.Location
 	if fieldI < fieldCount {
 		if expr.expression[indices[fieldI][0]:indices[fieldI][1]] != "" {
-			// try parse timezone
-			expr.timeZone = time.FixedZone(expr.expression[indices[fieldI][0]:indices[fieldI][1]], 0)
+			// try parse timezone, the lowered expression would not be found
+			// in the time zone database
+			zone := expr.expression[indices[fieldI][0]:indices[fieldI][1]]
+			if len(original) == len(expr.expression) {
+				zone = original[indices[fieldI][0]:indices[fieldI][1]]
+			}
+			expr.timeZone, err = time.LoadLocation(zone)
+			if err != nil {
+				if options.Strict {
+					return nil, newParseError(fmt.Errorf("unknown time zone '%s'", zone), source, indices[fieldI][0], len(zone))
+				}
+				expr.timeZone = time.FixedZone(zone, 0)
+			}
 		}
 	}
 	return &expr, nil
@@ -199,7 +318,8 @@ func Parse(systemdLine string) (*Expression, error) {
 // matches the cron expression `expr`.
 //
 // The `time.Location` of the returned time instant is the same as that of
-// `fromTime`.
+// `fromTime`, unless the expression carries its own time zone: `fromTime` is
+// then converted to it, and so is the returned time instant.
 //
 // The zero value of time.Time is returned if no matching time instant exists
 // or if a `fromTime` is itself a zero value.
@@ -211,6 +331,7 @@ func (expr *Expression) Next(fromTime time.Time) time.Time {
 	loc := fromTime.Location()
 	if expr.timeZone != nil {
 		loc = expr.timeZone
+		fromTime = fromTime.In(loc)
 	}
 	t := fromTime.Add(time.Second - time.Duration(fromTime.Nanosecond())*time.Nanosecond)
 
@@ -233,18 +354,19 @@ WRAP:
 		t = time.Date(t.Year(), time.Month(expr.monthList[i]), 1, 0, 0, 0, 0, loc)
 	}
 
-	expr.actualDaysOfMonthList = expr.calculateActualDaysOfMonth(t.Year(), int(t.Month()))
-	if len(expr.actualDaysOfMonthList) == 0 {
+	// kept local, Next may be called from several goroutines at once
+	actualDaysOfMonthList := expr.calculateActualDaysOfMonth(t.Year(), int(t.Month()))
+	if len(actualDaysOfMonthList) == 0 {
 		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
 		goto WRAP
 	}
 
 	v = t.Day()
-	if i := sort.SearchInts(expr.actualDaysOfMonthList, v); i == len(expr.actualDaysOfMonthList) {
+	if i := sort.SearchInts(actualDaysOfMonthList, v); i == len(actualDaysOfMonthList) {
 		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
 		goto WRAP
-	} else if v != expr.actualDaysOfMonthList[i] {
-		t = time.Date(t.Year(), t.Month(), expr.actualDaysOfMonthList[i], 0, 0, 0, 0, loc)
+	} else if v != actualDaysOfMonthList[i] {
+		t = time.Date(t.Year(), t.Month(), actualDaysOfMonthList[i], 0, 0, 0, 0, loc)
 
 		// in San Palo, before 2019, there may be no midnight (or multiple midnights)
 		// due to DST
@@ -336,7 +458,7 @@ SLOW_CLOCK:
 //
 // The time instants in the returned slice are in chronological ascending order.
 // The `time.Location` of the returned time instants is the same as that of
-// `fromTime`.
+// `fromTime`, unless the expression carries its own time zone, as with Next.
 //
 // A slice with len between [0-`n`] is returned, that is, if not enough existing
 // matching time instants exist, the number of returned entries will be less
@@ -359,3 +481,35 @@ func (expr *Expression) NextN(fromTime time.Time, n uint) []time.Time {
 	}
 	return nextTimes
 }
+
+/******************************************************************************/
+
+// Between returns the time instants at or after `from` and before `to` which
+// match the cron expression `expr`, in chronological ascending order. The
+// `time.Location` of the returned time instants is that of `from`, unless the
+// expression carries its own time zone.
+func (expr *Expression) Between(from, to time.Time) []time.Time {
+	var times []time.Time
+	for t := expr.Next(from.Add(-time.Nanosecond)); !t.IsZero() && t.Before(to); t = expr.Next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+/******************************************************************************/
+
+// DaysOfMonth returns the days of a month on which the expression elapses, in
+// ascending order. Days are those of the time zone of the expression when it
+// has one. An empty slice is returned when the year or month is not matched.
+func (expr *Expression) DaysOfMonth(year int, month time.Month) []int {
+	if !sortContains(expr.yearList, year) || !sortContains(expr.monthList, int(month)) {
+		return nil
+	}
+	return expr.calculateActualDaysOfMonth(year, int(month))
+}
+
+// Location returns the time zone named by the expression, nil when it has
+// none.
+func (expr *Expression) Location() *time.Location {
+	return expr.timeZone
+}
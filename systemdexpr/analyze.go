@@ -0,0 +1,144 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+)
+
+/******************************************************************************/
+
+// Layout of the timestamps printed by `systemd-analyze calendar`
+const analyzeTimeLayout = "Mon 2006-01-02 15:04:05 MST"
+
+// Lengths of a year and a month as used by systemd
+const (
+	analyzeYear  = 31557600 * time.Second
+	analyzeMonth = 2629800 * time.Second
+	analyzeWeek  = 7 * 24 * time.Hour
+	analyzeDay   = 24 * time.Hour
+)
+
+/******************************************************************************/
+
+// analyzeMain prints the expressions given as arguments the way
+// `systemd-analyze calendar` does.
+func analyzeMain(args []string) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s analyze [options] \"{cron expression}\"...\noptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	iterations := flags.Uint("iterations", 1, `number of elapses to show`)
+	baseTimeStr := flags.String("base-time", "", `systemd timestamp (i.e. "tomorrow 12:00") or whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") from which elapses are computed, now if not present`)
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return
+	}
+
+	now := clock.Now()
+	base := now
+	if *baseTimeStr != "" {
+		var err error
+		if base, err = parseInTime(*baseTimeStr); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse --base-time= parameter: %s\n", *baseTimeStr)
+			os.Exit(1)
+		}
+	}
+
+	if !analyze(os.Stdout, os.Stderr, flags.Args(), base, now, *iterations) {
+		os.Exit(1)
+	}
+}
+
+// analyze writes the analysis of each expression, and tells whether they all
+// could be parsed. Relative times are computed from `now`.
+func analyze(w, errw io.Writer, cronStrs []string, base, now time.Time, iterations uint) bool {
+	if iterations < 1 {
+		iterations = 1
+	}
+	ok := true
+	for i, cronStr := range cronStrs {
+		expr, err := systemdexpr.ParseWithOptions(cronStr, parseOptions)
+		if err != nil {
+			fmt.Fprintf(errw, "Failed to parse calendar specification '%s': %s\n", cronStr, err)
+			ok = false
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%15s: %s\n", "Original form", cronStr)
+		fmt.Fprintf(w, "%15s: %s\n", "Normalized form", expr)
+
+		elapses := expr.NextN(base, iterations)
+		if len(elapses) == 0 {
+			fmt.Fprintf(w, "%15s: %s\n", "Next elapse", "never")
+			continue
+		}
+		for j, elapse := range elapses {
+			label := "Next elapse"
+			if j > 0 {
+				label = fmt.Sprintf("Iteration #%d", j+1)
+			}
+			fmt.Fprintf(w, "%15s: %s\n", label, elapse.Format(analyzeTimeLayout))
+			if _, offset := elapse.Zone(); offset != 0 || elapse.Location().String() != "UTC" {
+				fmt.Fprintf(w, "%15s: %s\n", "(in UTC)", elapse.UTC().Format(analyzeTimeLayout))
+			}
+			fmt.Fprintf(w, "%15s: %s\n", "From now", formatRelative(elapse.Sub(now)))
+		}
+	}
+	return ok
+}
+
+/******************************************************************************/
+
+// formatRelative formats a duration as systemd does for relative timestamps,
+// such as "1 day 14h left" or "3min 20s ago".
+func formatRelative(d time.Duration) string {
+	suffix := "left"
+	if d < 0 {
+		d, suffix = -d, "ago"
+	}
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= analyzeYear:
+		return fmt.Sprintf("%s %s %s", plural(int64(d/analyzeYear), "year"), plural(int64(d%analyzeYear/analyzeMonth), "month"), suffix)
+	case d >= analyzeMonth:
+		return fmt.Sprintf("%s %s %s", plural(int64(d/analyzeMonth), "month"), plural(int64(d%analyzeMonth/analyzeDay), "day"), suffix)
+	case d >= analyzeWeek:
+		return fmt.Sprintf("%s %s %s", plural(int64(d/analyzeWeek), "week"), plural(int64(d%analyzeWeek/analyzeDay), "day"), suffix)
+	case d >= 2*analyzeDay:
+		return fmt.Sprintf("%d days %s", d/analyzeDay, suffix)
+	case d >= 25*time.Hour:
+		return fmt.Sprintf("1 day %dh %s", (d-analyzeDay)/time.Hour, suffix)
+	case d >= 6*time.Hour:
+		return fmt.Sprintf("%dh %s", d/time.Hour, suffix)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dmin %s", d/time.Hour, d%time.Hour/time.Minute, suffix)
+	case d >= 5*time.Minute:
+		return fmt.Sprintf("%dmin %s", d/time.Minute, suffix)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dmin %ds %s", d/time.Minute, d%time.Minute/time.Second, suffix)
+	case d >= time.Second:
+		return fmt.Sprintf("%ds %s", d/time.Second, suffix)
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%dms %s", d/time.Millisecond, suffix)
+	case d > 0:
+		return fmt.Sprintf("%dus %s", d/time.Microsecond, suffix)
+	}
+	return "now"
+}
@@ -0,0 +1,208 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+)
+
+/******************************************************************************/
+
+// Shades of the hourly heatmap, from no elapse to the most elapses
+var calShades = []string{" ", "░", "▒", "▓", "█"}
+
+var calWeekdays = []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"}
+
+// Width of a day of the month grid, count included
+const calCellWidth = 6
+
+/******************************************************************************/
+
+// calMonth holds the elapses of an expression in a month, per day and hour.
+type calMonth struct {
+	first  time.Time // midnight of the first day
+	days   int
+	counts [][24]int // indexed by day - 1
+}
+
+/******************************************************************************/
+
+// calMain prints a month grid of the days on which an expression elapses,
+// like `cal(1)`.
+func calMain(args []string) {
+	flags := flag.NewFlagSet("cal", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s cal [options] \"{cron expression}\"\noptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	monthStr := flags.String("month", "", `month to show (i.e. "2006-01"), the current month if not present`)
+	hours := flags.Bool("hours", false, `show an hourly heatmap instead of the month grid`)
+	color := flags.Bool("color", fileIsTerminal(os.Stdout), `highlight matching days`)
+	args = parseInterspersed(flags, args)
+
+	if len(args) != 1 {
+		flags.Usage()
+		return
+	}
+
+	expr, err := systemdexpr.ParseWithOptions(args[0], parseOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+		os.Exit(1)
+	}
+
+	loc := expr.Location()
+	if loc == nil {
+		loc = time.Local
+	}
+	month := clock.Now().In(loc)
+	if *monthStr != "" {
+		if month, err = time.ParseInLocation("2006-01", *monthStr, loc); err != nil {
+			fmt.Fprintf(os.Stderr, "# error: unparseable month: \"%s\"\n", *monthStr)
+			os.Exit(1)
+		}
+	}
+
+	m := countMonth(expr, month.Year(), month.Month(), loc)
+	if *hours {
+		writeHeatmap(os.Stdout, m)
+	} else {
+		writeMonthGrid(os.Stdout, m, *color)
+	}
+}
+
+func fileIsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+/******************************************************************************/
+
+// countMonth counts the elapses of an expression in each hour of the matching
+// days of a month.
+func countMonth(expr *systemdexpr.Expression, year int, month time.Month, loc *time.Location) calMonth {
+	m := calMonth{first: time.Date(year, month, 1, 0, 0, 0, 0, loc)}
+	m.days = m.first.AddDate(0, 1, -1).Day()
+	m.counts = make([][24]int, m.days)
+	for _, day := range expr.DaysOfMonth(year, month) {
+		if day > m.days {
+			continue
+		}
+		from := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		to := from.AddDate(0, 0, 1)
+		for t := expr.Next(from.Add(-time.Nanosecond)); !t.IsZero() && t.Before(to); t = expr.Next(t) {
+			t = t.In(loc)
+			m.counts[t.Day()-1][t.Hour()]++
+		}
+	}
+	return m
+}
+
+func (m calMonth) dayCount(day int) int {
+	n := 0
+	for _, c := range m.counts[day-1] {
+		n += c
+	}
+	return n
+}
+
+// formatCount shortens large counts so that they fit in a grid cell.
+func formatCount(n int) string {
+	switch {
+	case n == 0:
+		return ""
+	case n < 1000:
+		return fmt.Sprint(n)
+	case n < 1000000:
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return fmt.Sprintf("%dM", n/1000000)
+}
+
+/******************************************************************************/
+
+// writeMonthGrid writes a month grid, weeks starting on Monday, in which each
+// day is followed by how many times the expression elapses on it.
+func writeMonthGrid(w io.Writer, m calMonth, color bool) {
+	width := 7*(calCellWidth+1) - 1
+	title := m.first.Format("January 2006")
+	fmt.Fprintf(w, "%*s\n", (width+len(title))/2, title)
+	header := make([]string, len(calWeekdays))
+	for i, name := range calWeekdays {
+		header[i] = fmt.Sprintf("%-*s", calCellWidth, name)
+	}
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(header, " "), " "))
+
+	column := (int(m.first.Weekday()) + 6) % 7
+	fmt.Fprint(w, strings.Repeat(" ", column*(calCellWidth+1)))
+	for day := 1; day <= m.days; day++ {
+		count := m.dayCount(day)
+		cell := fmt.Sprintf("%2d", day)
+		if color && count > 0 {
+			cell = "\x1b[7m" + cell + "\x1b[0m"
+		}
+		cell += fmt.Sprintf(" %-*s", calCellWidth-3, formatCount(count))
+		if column == 6 || day == m.days {
+			fmt.Fprintln(w, strings.TrimRight(cell, " "))
+			column = 0
+			continue
+		}
+		fmt.Fprint(w, cell+" ")
+		column++
+	}
+}
+
+// calShade returns the shade of a count: any elapse gets at least the
+// lightest shade and the most elapses the darkest.
+func calShade(count, max int) int {
+	darkest := len(calShades) - 1
+	switch {
+	case count == 0:
+		return 0
+	case max <= 1:
+		return darkest
+	}
+	return 1 + (count-1)*(darkest-1)/(max-1)
+}
+
+// writeHeatmap writes a line per day of the month, with a shade per hour
+// telling how many times the expression elapses in it.
+func writeHeatmap(w io.Writer, m calMonth) {
+	max := 0
+	for _, hours := range m.counts {
+		for _, c := range hours {
+			if c > max {
+				max = c
+			}
+		}
+	}
+
+	fmt.Fprintln(w, m.first.Format("January 2006"))
+	fmt.Fprint(w, "      ")
+	for h := 0; h < 24; h++ {
+		fmt.Fprint(w, h/10)
+	}
+	fmt.Fprint(w, "\n      ")
+	for h := 0; h < 24; h++ {
+		fmt.Fprint(w, h%10)
+	}
+	fmt.Fprintln(w)
+	for day := 1; day <= m.days; day++ {
+		date := m.first.AddDate(0, 0, day-1)
+		line := fmt.Sprintf("%s %2d ", calWeekdays[(int(date.Weekday())+6)%7], day)
+		for _, c := range m.counts[day-1] {
+			line += calShades[calShade(c, max)]
+		}
+		if count := m.dayCount(day); count > 0 {
+			line += fmt.Sprintf(" %d", count)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
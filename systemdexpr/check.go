@@ -0,0 +1,124 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aneustroev/systemdexpr"
+	"github.com/aneustroev/systemdexpr/unitfile"
+)
+
+/******************************************************************************/
+
+// Settings of the [Timer] section which trigger a timer
+var timerTriggers = []string{
+	"OnCalendar", "OnActiveSec", "OnBootSec", "OnStartupSec",
+	"OnUnitActiveSec", "OnUnitInactiveSec", "OnClockChange", "OnTimezoneChange",
+}
+
+/******************************************************************************/
+
+// checkMain validates every `.timer` file found in the directories given as
+// arguments.
+func checkMain(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s check {directory}...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return
+	}
+
+	failures := 0
+	for _, root := range flags.Args() {
+		n, err := check(os.Stdout, root)
+		failures += n
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+			os.Exit(1)
+		}
+	}
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// check reports each invalid timer of a directory tree as `file:line:
+// message`, and returns how many problems were found. Expressions are parsed
+// strictly, as systemd would read them.
+func check(w io.Writer, root string) (int, error) {
+	failures := 0
+	err := walkTimers(root, func(path string, err error) {
+		var unit *unitfile.Unit
+		if err == nil {
+			unit, err = unitfile.Load(path)
+		}
+		if err != nil {
+			failures++
+			if _, ok := err.(*unitfile.SyntaxError); ok {
+				fmt.Fprintln(w, err)
+			} else {
+				fmt.Fprintf(w, "%s: %s\n", path, err)
+			}
+			return
+		}
+
+		for _, entry := range unit.Values("Timer", "OnCalendar") {
+			if _, err := systemdexpr.ParseWithOptions(entry.Value, systemdexpr.Options{Strict: true, MatchBothDays: true}); err != nil {
+				failures++
+				fmt.Fprintf(w, "%s:%d: OnCalendar=%s: %s\n", entry.File, entry.Line, entry.Value, err)
+			}
+		}
+
+		triggered := false
+		for _, key := range timerTriggers {
+			if len(unit.Values("Timer", key)) > 0 {
+				triggered = true
+			}
+		}
+		if !triggered {
+			failures++
+			fmt.Fprintf(w, "%s: timer has no trigger\n", path)
+		}
+	})
+	return failures, err
+}
+
+// walkTimers calls fn with the path of each `.timer` file of a directory
+// tree. Symlinks, such as those of timers.target.wants enabling units of
+// /usr/lib/systemd/system, are followed and each unit is visited once, units
+// masked by a link to /dev/null being skipped. A dangling link is given with
+// the error resolving it.
+func walkTimers(root string, fn func(path string, err error)) error {
+	seen := make(map[string]bool)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".timer") {
+			return nil
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			fn(path, err)
+			return nil
+		}
+		if resolved == os.DevNull || seen[resolved] {
+			return nil
+		}
+		seen[resolved] = true
+		fn(path, nil)
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+	"github.com/aneustroev/systemdexpr/unitfile"
+)
+
+/******************************************************************************/
+
+// Most elapses listed for a single timer by forecast
+const forecastMaxElapses = 1000
+
+// A forecastEntry is an elapse of a timer unit.
+type forecastEntry struct {
+	elapse    time.Time
+	unit      string
+	activates string
+}
+
+/******************************************************************************/
+
+// forecastMain prints the merged timeline of the timers found in the
+// directories given as arguments, like `systemctl list-timers`.
+func forecastMain(args []string) {
+	flags := flag.NewFlagSet("forecast", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s forecast [options] {directory}...\noptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	window := flags.Duration("window", 24*time.Hour, `length of the forecast window following the base time`)
+	since := flags.Duration("since", 0, `length of the window preceding the base time`)
+	baseTimeStr := flags.String("base-time", "", `systemd timestamp (i.e. "tomorrow 12:00") or whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") from which relative times are computed, now if not present`)
+	roots := parseInterspersed(flags, args)
+
+	if len(roots) == 0 {
+		flags.Usage()
+		return
+	}
+
+	base, err := parseInTime(*baseTimeStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "# error: unparseable time value: \"%s\"\n", *baseTimeStr)
+		os.Exit(1)
+	}
+
+	var entries []forecastEntry
+	for _, root := range roots {
+		found, truncated, err := forecastTimers(root, base.Add(-*since), base.Add(*window))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+			os.Exit(1)
+		}
+		for _, unit := range truncated {
+			fmt.Fprintf(os.Stderr, "# warning: more than %d elapses of %s in the forecast window, the first ones are listed\n", forecastMaxElapses, unit)
+		}
+		entries = append(entries, found...)
+	}
+	writeForecast(os.Stdout, entries, base)
+}
+
+// parseInterspersed parses flags found before, between or after positional
+// arguments, and returns the latter.
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = flags.Parse(args)
+		if flags.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+/******************************************************************************/
+
+// forecastTimers returns the elapses from `from` to `to` of the calendar
+// timers of a directory tree, and the timers of which only the first
+// forecastMaxElapses are returned. Invalid timers are skipped, see `check`.
+func forecastTimers(root string, from, to time.Time) (entries []forecastEntry, truncated []string, err error) {
+	err = walkTimers(root, func(path string, err error) {
+		if err != nil {
+			return
+		}
+		unit, err := unitfile.Load(path)
+		if err != nil {
+			return
+		}
+
+		name := filepath.Base(path)
+		activates := strings.TrimSuffix(name, ".timer") + ".service"
+		if values := unit.Values("Timer", "Unit"); len(values) > 0 {
+			activates = values[len(values)-1].Value
+		}
+
+		// several expressions of a unit may elapse at once
+		seen := make(map[time.Time]bool)
+		for _, entry := range unit.Values("Timer", "OnCalendar") {
+			expr, err := systemdexpr.ParseWithOptions(entry.Value, systemdexpr.Options{Strict: true, MatchBothDays: true})
+			if err != nil {
+				continue
+			}
+			elapses := expr.NextN(from.Add(-time.Nanosecond), forecastMaxElapses+1)
+			for i, elapse := range elapses {
+				if !elapse.Before(to) {
+					break
+				}
+				if i == forecastMaxElapses {
+					if len(truncated) == 0 || truncated[len(truncated)-1] != name {
+						truncated = append(truncated, name)
+					}
+					break
+				}
+				elapse = elapse.In(from.Location())
+				if !seen[elapse] {
+					seen[elapse] = true
+					entries = append(entries, forecastEntry{elapse: elapse, unit: name, activates: activates})
+				}
+			}
+		}
+	})
+	return entries, truncated, err
+}
+
+// writeForecast writes the entries sorted by time, with the time left until
+// those following `base` and the time passed since those preceding it.
+func writeForecast(w io.Writer, entries []forecastEntry, base time.Time) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].elapse.Equal(entries[j].elapse) {
+			return entries[i].elapse.Before(entries[j].elapse)
+		}
+		return entries[i].unit < entries[j].unit
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tLEFT\tPASSED\tUNIT\tACTIVATES")
+	for _, entry := range entries {
+		left, passed := "-", "-"
+		if entry.elapse.Before(base) {
+			passed = formatRelative(entry.elapse.Sub(base))
+		} else {
+			left = formatRelative(entry.elapse.Sub(base))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", entry.elapse.Format(analyzeTimeLayout), left, passed, entry.unit, entry.activates)
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(w, "\n%d elapses listed.\n", len(entries))
+}
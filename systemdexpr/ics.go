@@ -0,0 +1,60 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+)
+
+/******************************************************************************/
+
+// icsMain writes the elapses of the expressions given as arguments as an
+// iCalendar file, each argument being `label=expression` or `expression`.
+func icsMain(args []string) {
+	flags := flag.NewFlagSet("ics", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s ics [options] \"[label=]{cron expression}\"...\noptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	fromStr := flags.String("t", "", `systemd timestamp (i.e. "tomorrow 12:00") or whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") at which the calendar starts, now if not present`)
+	window := flags.Duration("w", 30*24*time.Hour, `length of the calendar window`)
+	duration := flags.Duration("d", 0, `duration of each event`)
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return
+	}
+
+	from, err := parseInTime(*fromStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "# error: unparseable time value: \"%s\"\n", *fromStr)
+		os.Exit(1)
+	}
+
+	entries := make([]systemdexpr.CalendarEntry, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		label, cronStr, found := strings.Cut(arg, "=")
+		if !found {
+			label, cronStr = arg, arg
+		}
+		expr, err := systemdexpr.ParseWithOptions(cronStr, parseOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+			os.Exit(1)
+		}
+		entries = append(entries, systemdexpr.CalendarEntry{Label: label, Expression: expr, Duration: *duration})
+	}
+
+	err = systemdexpr.WriteCalendar(os.Stdout, from, from.Add(*window), entries...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
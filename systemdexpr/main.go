@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/aneustroev/systemdexpr"
@@ -15,23 +16,52 @@ import (
 
 var (
 	usage = func() {
-		fmt.Fprintf(os.Stderr, "usage:\n  %s [options] \"{cron expression}\"\noptions:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "usage:\n  %s [options] \"{cron expression}\"\n  %s analyze [options] \"{cron expression}\"...\n  %s cal [options] \"{cron expression}\"\n  %s check {directory}...\n  %s forecast [options] {directory}...\n  %s ics [options] \"[label=]{cron expression}\"...\n  %s repl [options]\noptions:\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
 	inTimeStr     string
 	outTimeCount  uint
 	outTimeLayout string
+	outFormat     string
+	// clock tells the time, tests replace it with a fake one
+	clock = systemdexpr.RealClock
+	// parseOptions evaluates the expressions as systemd does
+	parseOptions = systemdexpr.Options{MatchBothDays: true}
+	// rfc3339YearFinder tells the start of a partial RFC3339 time value
+	rfc3339YearFinder = regexp.MustCompile(`^\d{4}`)
 )
 
 /******************************************************************************/
 
 func main() {
-	var err error
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "ics":
+			icsMain(os.Args[2:])
+			return
+		case "analyze":
+			analyzeMain(os.Args[2:])
+			return
+		case "cal":
+			calMain(os.Args[2:])
+			return
+		case "check":
+			checkMain(os.Args[2:])
+			return
+		case "forecast":
+			forecastMain(os.Args[2:])
+			return
+		case "repl":
+			replMain(os.Args[2:])
+			return
+		}
+	}
 
 	flag.Usage = usage
-	flag.StringVar(&inTimeStr, "t", "", `whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") against which the cron expression is evaluated, now if not present`)
+	flag.StringVar(&inTimeStr, "t", "", `systemd timestamp (i.e. "2006-01-02 15:04:05", "tomorrow", "+3h" or "@1136214245") or whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") against which the cron expression is evaluated, now if not present`)
 	flag.UintVar(&outTimeCount, "n", 1, `number of resulting time values to output`)
 	flag.StringVar(&outTimeLayout, "l", "Mon, 02 Jan 2006 15:04:05 MST", `Go-compliant time layout to use for outputting time value(s), see <http://golang.org/pkg/time/#pkg-constants>`)
+	flag.StringVar(&outFormat, "o", outputText, `output format: text, json, ndjson or csv`)
 	flag.Parse()
 
 	cronStr := flag.Arg(0)
@@ -39,63 +69,94 @@ func main() {
 		flag.Usage()
 		return
 	}
-
-	inTime := time.Now()
-	inTimeLayout := ""
-	timeStrLen := len(inTimeStr)
-	if timeStrLen == 2 {
-		inTimeLayout = "06"
-	} else if timeStrLen >= 4 {
-		inTimeLayout += "2006"
-		if timeStrLen >= 7 {
-			inTimeLayout += "-01"
-			if timeStrLen >= 10 {
-				inTimeLayout += "-02"
-				if timeStrLen >= 13 {
-					inTimeLayout += "T15"
-					if timeStrLen >= 16 {
-						inTimeLayout += ":04"
-						if timeStrLen >= 19 {
-							inTimeLayout += ":05"
-							if timeStrLen >= 20 {
-								inTimeLayout += "Z07:00"
-							}
-						}
-					}
-				}
-			}
-		}
+	if !validOutputFormat(outFormat) {
+		fmt.Fprintf(os.Stderr, "# error: unknown output format: \"%s\"\n", outFormat)
+		os.Exit(1)
 	}
 
-	if len(inTimeLayout) > 0 {
-		// default to local time zone
-		if timeStrLen < 20 {
-			inTime, err = time.ParseInLocation(inTimeLayout, inTimeStr, time.Local)
+	inTime, err := parseInTime(inTimeStr)
+	if err != nil {
+		if outFormat != outputText {
+			_ = writeError(os.Stderr, outFormat, cronStr, fmt.Errorf("unparseable time value: \"%s\"", inTimeStr))
 		} else {
-			inTime, err = time.Parse(inTimeLayout, inTimeStr)
-		}
-		if err != nil {
 			fmt.Fprintf(os.Stderr, "# error: unparseable time value: \"%s\"\n", inTimeStr)
-			os.Exit(1)
 		}
+		os.Exit(1)
 	}
 
-	expr, err := systemdexpr.Parse(cronStr)
+	expr, err := systemdexpr.ParseWithOptions(cronStr, parseOptions)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+		if outFormat != outputText {
+			_ = writeError(os.Stderr, outFormat, cronStr, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+		}
 		os.Exit(1)
 	}
 
+	if outTimeCount < 1 {
+		outTimeCount = 1
+	}
+	if outFormat != outputText {
+		err = writeResult(os.Stdout, outFormat, cronStr, expr.String(), inTime, expr.NextN(inTime, outTimeCount))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Anything on the output which starts with '#' can be ignored if the caller
 	// is interested only in the time values. There is only one time
 	// value per line, and they are always in chronological ascending order.
 	fmt.Printf("# \"%s\" + \"%s\" =\n", cronStr, inTime.Format(time.RFC3339))
 
-	if outTimeCount < 1 {
-		outTimeCount = 1
-	}
 	outTimes := expr.NextN(inTime, outTimeCount)
 	for _, outTime := range outTimes {
 		fmt.Println(outTime.Format(outTimeLayout))
 	}
 }
+
+/******************************************************************************/
+
+// parseInTime reads a systemd timestamp, such as "tomorrow 12:00" or "+3h",
+// or a whole or partial RFC3339 time value. It returns now if empty.
+func parseInTime(inTimeStr string) (time.Time, error) {
+	if inTimeStr == "" {
+		return clock.Now(), nil
+	}
+	t, err := systemdexpr.ParseTimestamp(inTimeStr, clock.Now())
+	if err == nil {
+		return t, nil
+	}
+	// partial RFC3339 values start with the year
+	if !rfc3339YearFinder.MatchString(inTimeStr) {
+		return time.Time{}, err
+	}
+	inTimeLayout := "2006"
+	timeStrLen := len(inTimeStr)
+	if timeStrLen >= 7 {
+		inTimeLayout += "-01"
+		if timeStrLen >= 10 {
+			inTimeLayout += "-02"
+			if timeStrLen >= 13 {
+				inTimeLayout += "T15"
+				if timeStrLen >= 16 {
+					inTimeLayout += ":04"
+					if timeStrLen >= 19 {
+						inTimeLayout += ":05"
+						if timeStrLen >= 20 {
+							inTimeLayout += "Z07:00"
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// default to local time zone
+	if timeStrLen < 20 {
+		return time.ParseInLocation(inTimeLayout, inTimeStr, time.Local)
+	}
+	return time.Parse(inTimeLayout, inTimeStr)
+}
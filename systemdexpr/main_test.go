@@ -0,0 +1,249 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aneustroev/systemdexpr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************************************************/
+
+var update = flag.Bool("update", false, "update golden files")
+
+// golden compares output with testdata/<name>.golden, or rewrites it with
+// `-update`.
+func golden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.WriteFile(path, output, 0o644))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(output))
+}
+
+/******************************************************************************/
+
+func TestAnalyze(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	base := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		exprs      []string
+		base       time.Time
+		iterations uint
+	}{
+		{"analyze-utc", []string{"Mon..Fri 09:00", "*:0/15"}, base, 3},
+		{"analyze-berlin", []string{"quarterly", "Sat,Sun *-06..08-* 08:05:40"}, base.In(berlin), 2},
+		{"analyze-never", []string{"2019-*-* 00:00"}, base, 1},
+		{"analyze-far", []string{"*-02-29 00:00", "*-*~01 23:59:59"}, base, 2},
+	}
+	for _, c := range cases {
+		var out, errOut bytes.Buffer
+		ok := analyze(&out, &errOut, c.exprs, c.base, c.base, c.iterations)
+		assert.Truef(t, ok, "analyzing %q: %s", c.exprs, errOut.String())
+		golden(t, c.name, out.Bytes())
+	}
+
+	var out, errOut bytes.Buffer
+	assert.False(t, analyze(&out, &errOut, []string{"Mon..Fri 09:00", "*-13-01"}, base, base, 1))
+	assert.Contains(t, errOut.String(), "Failed to parse calendar specification '*-13-01'")
+}
+
+func TestFormatRelative(t *testing.T) {
+	cases := []struct {
+		d        time.Duration
+		relative string
+	}{
+		{0, "now"},
+		{3 * time.Second, "3s left"},
+		{-(3*time.Minute + 20*time.Second), "3min 20s ago"},
+		{10 * time.Minute, "10min left"},
+		{2*time.Hour + 5*time.Minute, "2h 5min left"},
+		{14 * time.Hour, "14h left"},
+		{38 * time.Hour, "1 day 14h left"},
+		{3 * 24 * time.Hour, "3 days left"},
+		{9 * 24 * time.Hour, "1 week 2 days left"},
+		{45 * 24 * time.Hour, "1 month 14 days left"},
+		{800 * 24 * time.Hour, "2 years 2 months left"},
+	}
+	for _, c := range cases {
+		assert.Equalf(t, c.relative, formatRelative(c.d), "formatting %s", c.d)
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	base := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	elapses := []time.Time{
+		time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, time.October, 26, 9, 0, 0, 0, time.UTC),
+	}
+	for _, format := range []string{outputJSON, outputNDJSON, outputCSV} {
+		var out bytes.Buffer
+		require.NoError(t, writeResult(&out, format, "Mon 09:00", "Mon *-*-* 09:00:00", base, elapses))
+		golden(t, "result-"+format, out.Bytes())
+
+		out.Reset()
+		require.NoError(t, writeError(&out, format, "*-13-01", errors.New("syntax error in month field: '13'")))
+		golden(t, "error-"+format, out.Bytes())
+	}
+	for _, format := range []string{outputJSON, outputNDJSON, outputCSV} {
+		var out bytes.Buffer
+		require.NoError(t, writeResult(&out, format, "*-02-30", "*-02-30 00:00:00", base, nil))
+		golden(t, "never-"+format, out.Bytes())
+	}
+	assert.Error(t, writeResult(&bytes.Buffer{}, "yaml", "", "", base, nil))
+}
+
+func TestCheck(t *testing.T) {
+	var out bytes.Buffer
+	failures, err := check(&out, filepath.Join("testdata", "check"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, failures)
+	golden(t, "check", out.Bytes())
+
+	_, err = check(&out, filepath.Join("testdata", "missing"))
+	assert.Error(t, err)
+}
+
+func TestForecast(t *testing.T) {
+	base := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.UTC)
+	entries, truncated, err := forecastTimers(filepath.Join("testdata", "forecast"), base.Add(-6*time.Hour), base.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, truncated)
+
+	var out bytes.Buffer
+	writeForecast(&out, entries, base)
+	golden(t, "forecast", out.Bytes())
+
+	// a year of a timer elapsing each second
+	entries, truncated, err = forecastTimers(filepath.Join("testdata", "forecast-busy"), base, base.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Len(t, entries, forecastMaxElapses)
+	assert.Equal(t, []string{"busy.timer"}, truncated)
+}
+
+func TestParseInterspersed(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	window := flags.Duration("window", time.Hour, "")
+	roots := parseInterspersed(flags, []string{"a", "--window", "24h", "b"})
+	assert.Equal(t, []string{"a", "b"}, roots)
+	assert.Equal(t, 24*time.Hour, *window)
+}
+
+func TestParseInTime(t *testing.T) {
+	cases := map[string]time.Time{
+		"2026-10-16 12:00":     time.Date(2026, time.October, 16, 12, 0, 0, 0, time.Local),
+		"@1700000000":          time.Unix(1700000000, 0),
+		"2026-10-16T12:00 UTC": time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC),
+		// partial RFC3339 values are still accepted
+		"2026-10":       time.Date(2026, time.October, 1, 0, 0, 0, 0, time.Local),
+		"2026-10-16T12": time.Date(2026, time.October, 16, 12, 0, 0, 0, time.Local),
+	}
+	for s, expected := range cases {
+		inTime, err := parseInTime(s)
+		require.NoError(t, err, s)
+		assert.True(t, expected.Equal(inTime), "%s: expected %s, got %s", s, expected, inTime)
+	}
+
+	now := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.UTC)
+	defer func(saved systemdexpr.Clock) { clock = saved }(clock)
+	clock = systemdexpr.NewFakeClock(now)
+	for s, expected := range map[string]time.Time{"": now, "+3h": now.Add(3 * time.Hour), "tomorrow": time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)} {
+		inTime, err := parseInTime(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, inTime, s)
+	}
+
+	for _, s := range []string{"soon", "abc", "12"} {
+		_, err := parseInTime(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCal(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	mustParse := func(s string) *systemdexpr.Expression {
+		expr, err := systemdexpr.ParseWithOptions(s, parseOptions)
+		require.NoError(t, err)
+		return expr
+	}
+	cases := []struct {
+		name  string
+		expr  *systemdexpr.Expression
+		month time.Time
+		hours bool
+		color bool
+	}{
+		{"cal-first-weekdays", mustParse("Mon..Fri *-*-01..07 09,17:00"), time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), false, false},
+		{"cal-color", mustParse("Fri *-*-13 00:00"), time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), false, true},
+		{"cal-workdays", systemdexpr.MustParseCron("0 0 1W,LW * *"), time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), false, false},
+		{"cal-dst", mustParse("*:0/15 Europe/Berlin"), time.Date(2026, time.October, 1, 0, 0, 0, 0, berlin), false, false},
+		{"cal-heatmap", mustParse("Mon..Fri 09..17:0/30,15"), time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), true, false},
+	}
+	for _, c := range cases {
+		m := countMonth(c.expr, c.month.Year(), c.month.Month(), c.month.Location())
+		var out bytes.Buffer
+		if c.hours {
+			writeHeatmap(&out, m)
+		} else {
+			writeMonthGrid(&out, m, c.color)
+		}
+		golden(t, c.name, out.Bytes())
+	}
+
+	assert.Equal(t, 0, calShade(0, 10))
+	assert.Equal(t, 1, calShade(1, 10))
+	assert.Equal(t, len(calShades)-1, calShade(10, 10))
+	assert.Equal(t, len(calShades)-1, calShade(1, 1))
+	assert.Equal(t, "12k", formatCount(12345))
+}
+
+/******************************************************************************/
+
+func TestRepl(t *testing.T) {
+	base := time.Date(2026, time.October, 18, 12, 30, 0, 0, time.UTC)
+	state := replState{count: 2, loc: time.UTC, clock: systemdexpr.NewFakeClock(base)}
+	input := `Mon..Fri 09:00
+*-13-01
+Mon -
+-
+Mon,Tüe 25:00
+Mon *-*-* 10:00 Zürich extra
+
+:n 3
+*:0/20
+:tz Europe/Berlin
+:base 2026-12-31T23:00:00Z
+Sat *-*-* 25:00
+daily
+:base now
+:n 0
+:tz Mars/Olympus
+:bogus
+:quit
+weekly
+`
+	var out bytes.Buffer
+	require.NoError(t, repl(strings.NewReader(input), &out, &state, false))
+	golden(t, "repl", out.Bytes())
+	assert.Equal(t, uint(3), state.count)
+	assert.Equal(t, "Europe/Berlin", state.loc.String())
+	assert.True(t, state.base.IsZero())
+}
@@ -0,0 +1,115 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+/******************************************************************************/
+
+// Machine-readable output formats
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputCSV    = "csv"
+)
+
+type elapseRecord struct {
+	Time string `json:"time"`
+	Unix int64  `json:"unix"`
+}
+
+type resultRecord struct {
+	Original   string         `json:"original"`
+	Normalized string         `json:"normalized"`
+	Base       string         `json:"base"`
+	Elapses    []elapseRecord `json:"elapses"`
+}
+
+// ndjsonRecord is a single elapse, flattened with its expression, or the
+// expression alone when it does not elapse.
+type ndjsonRecord struct {
+	Original   string `json:"original"`
+	Normalized string `json:"normalized"`
+	Base       string `json:"base"`
+	*elapseRecord
+}
+
+type errorRecord struct {
+	Original string `json:"original"`
+	Error    string `json:"error"`
+}
+
+/******************************************************************************/
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case outputText, outputJSON, outputNDJSON, outputCSV:
+		return true
+	}
+	return false
+}
+
+// writeResult writes the elapses of an expression in a machine-readable
+// format. The expression is written even if it does not elapse, with empty
+// elapse fields.
+func writeResult(w io.Writer, format, original, normalized string, base time.Time, elapses []time.Time) error {
+	records := make([]elapseRecord, len(elapses))
+	for i, elapse := range elapses {
+		records[i] = elapseRecord{Time: elapse.Format(time.RFC3339), Unix: elapse.Unix()}
+	}
+	baseStr := base.Format(time.RFC3339)
+
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resultRecord{Original: original, Normalized: normalized, Base: baseStr, Elapses: records})
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		if len(records) == 0 {
+			return enc.Encode(ndjsonRecord{Original: original, Normalized: normalized, Base: baseStr})
+		}
+		for i := range records {
+			if err := enc.Encode(ndjsonRecord{Original: original, Normalized: normalized, Base: baseStr, elapseRecord: &records[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"original", "normalized", "base", "time", "unix"})
+		if len(records) == 0 {
+			_ = cw.Write([]string{original, normalized, baseStr, "", ""})
+		}
+		for _, record := range records {
+			_ = cw.Write([]string{original, normalized, baseStr, record.Time, strconv.FormatInt(record.Unix, 10)})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format '%s'", format)
+}
+
+// writeError writes an error about an expression in a machine-readable
+// format.
+func writeError(w io.Writer, format, original string, err error) error {
+	switch format {
+	case outputJSON, outputNDJSON:
+		return json.NewEncoder(w).Encode(errorRecord{Original: original, Error: err.Error()})
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"original", "error"})
+		_ = cw.Write([]string{original, err.Error()})
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format '%s'", format)
+}
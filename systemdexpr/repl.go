@@ -0,0 +1,196 @@
+package main
+
+/******************************************************************************/
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aneustroev/systemdexpr"
+)
+
+/******************************************************************************/
+
+const replHelp = `Type an expression to see its normalized form, its description and its next
+elapses. Commands:
+  :base [time]  show or set the base time, a systemd timestamp or a whole or
+                partial RFC3339 time value, "now" to follow the clock
+  :tz [zone]    show or set the time zone of expressions which name none
+  :n [count]    show or set how many elapses to show
+  :help         show this help
+  :quit         leave
+`
+
+/******************************************************************************/
+
+// replState holds the settings changed by the commands of the REPL.
+type replState struct {
+	base  time.Time // zero to follow the clock
+	loc   *time.Location
+	count uint
+	clock systemdexpr.Clock
+}
+
+func (s *replState) baseTime() time.Time {
+	base := s.base
+	if base.IsZero() {
+		base = s.clock.Now()
+	}
+	return base.In(s.loc)
+}
+
+/******************************************************************************/
+
+// replMain reads expressions from the standard input and explains each of
+// them.
+func replMain(args []string) {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n  %s repl [options]\noptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	baseTimeStr := flags.String("base-time", "", `systemd timestamp (i.e. "tomorrow 12:00") or whole or partial RFC3339 time value (i.e. "2006-01-02T15:04:05Z07:00") from which elapses are computed, now if not present`)
+	zone := flags.String("tz", "Local", `time zone of the expressions which name none`)
+	count := flags.Uint("n", 3, `number of elapses to show`)
+	_ = flags.Parse(args)
+
+	state := replState{count: *count, clock: clock}
+	var err error
+	if *baseTimeStr != "" {
+		if state.base, err = parseInTime(*baseTimeStr); err != nil {
+			fmt.Fprintf(os.Stderr, "# error: unparseable time value: \"%s\"\n", *baseTimeStr)
+			os.Exit(1)
+		}
+	}
+	if state.loc, err = time.LoadLocation(*zone); err != nil {
+		fmt.Fprintf(os.Stderr, "# error: unknown time zone: \"%s\"\n", *zone)
+		os.Exit(1)
+	}
+
+	if err := repl(os.Stdin, os.Stdout, &state, fileIsTerminal(os.Stdin)); err != nil {
+		fmt.Fprintf(os.Stderr, "# %s: %s\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
+
+// repl explains each expression read from `r` and runs the commands found
+// among them, until `:quit` or the end of the input. A prompt is written
+// before each line when `prompt` is set.
+func repl(r io.Reader, w io.Writer, state *replState, prompt bool) error {
+	scanner := bufio.NewScanner(r)
+	for {
+		if prompt {
+			fmt.Fprint(w, "> ")
+		}
+		if !scanner.Scan() {
+			if prompt {
+				fmt.Fprintln(w)
+			}
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, ":"):
+			if !replCommand(w, state, line) {
+				return nil
+			}
+		default:
+			replExplain(w, state, line)
+		}
+	}
+}
+
+// replCommand runs a command, and tells whether the REPL goes on.
+func replCommand(w io.Writer, state *replState, line string) bool {
+	fields := strings.Fields(line)
+	name, arg := fields[0], strings.Join(fields[1:], " ")
+	switch name {
+	case ":quit", ":q":
+		return false
+	case ":help", ":h":
+		fmt.Fprint(w, replHelp)
+	case ":base":
+		switch arg {
+		case "":
+		case "now":
+			state.base = time.Time{}
+		default:
+			base, err := parseInTime(arg)
+			if err != nil {
+				fmt.Fprintf(w, "error: unparseable time value: \"%s\"\n", arg)
+				return true
+			}
+			state.base = base
+		}
+		if state.base.IsZero() {
+			fmt.Fprintf(w, "base time: now (%s)\n", state.baseTime().Format(analyzeTimeLayout))
+		} else {
+			fmt.Fprintf(w, "base time: %s\n", state.baseTime().Format(analyzeTimeLayout))
+		}
+	case ":tz":
+		if arg != "" {
+			loc, err := time.LoadLocation(arg)
+			if err != nil {
+				fmt.Fprintf(w, "error: unknown time zone: \"%s\"\n", arg)
+				return true
+			}
+			state.loc = loc
+		}
+		fmt.Fprintf(w, "time zone: %s\n", state.loc)
+	case ":n":
+		if arg != "" {
+			count, err := strconv.ParseUint(arg, 10, 0)
+			if err != nil || count < 1 {
+				fmt.Fprintf(w, "error: invalid count: \"%s\"\n", arg)
+				return true
+			}
+			state.count = uint(count)
+		}
+		fmt.Fprintf(w, "elapses shown: %d\n", state.count)
+	default:
+		fmt.Fprintf(w, "error: unknown command \"%s\", see :help\n", name)
+	}
+	return true
+}
+
+// replExplain writes the normalized form, the description and the next
+// elapses of an expression, or where it is malformed. Expressions which name
+// no time zone are evaluated in the one of the state.
+func replExplain(w io.Writer, state *replState, line string) {
+	expr, err := systemdexpr.ParseWithOptions(line, parseOptions)
+	if err != nil {
+		var parseErr *systemdexpr.ParseError
+		if errors.As(err, &parseErr) {
+			// the caret is placed in runes, not bytes, under non-ASCII input
+			offset := utf8.RuneCountInString(parseErr.Expression[:parseErr.Offset])
+			fmt.Fprintf(w, "  %s\n  %s%s\n", parseErr.Expression, strings.Repeat(" ", offset), strings.Repeat("^", utf8.RuneCountInString(parseErr.Token)))
+		}
+		fmt.Fprintf(w, "error: %s\n", err)
+		return
+	}
+
+	fmt.Fprintf(w, "%15s: %s\n", "Normalized form", expr)
+	fmt.Fprintf(w, "%15s: %s\n", "Description", systemdexpr.Describe(expr))
+	base := state.baseTime()
+	elapses := expr.NextN(base, state.count)
+	if len(elapses) == 0 {
+		fmt.Fprintf(w, "%15s: %s\n", "Next elapse", "never")
+	}
+	for i, elapse := range elapses {
+		label := "Next elapse"
+		if i > 0 {
+			label = fmt.Sprintf("Iteration #%d", i+1)
+		}
+		fmt.Fprintf(w, "%15s: %s (%s)\n", label, elapse.Format(analyzeTimeLayout), formatRelative(elapse.Sub(base)))
+	}
+}
@@ -0,0 +1,191 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/******************************************************************************/
+
+// A Clock tells the time and makes timers. Code which needs the current time
+// takes a Clock so that tests can substitute a FakeClock for RealClock.
+type Clock interface {
+	Now() time.Time
+	// Monotonic returns the time elapsed since an arbitrary origin, which
+	// unlike Now does not follow changes of the wall clock.
+	Monotonic() time.Duration
+	NewTimer(d time.Duration) Timer
+	After(d time.Duration) <-chan time.Time
+}
+
+// A Timer is the time.Timer of a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the Clock of the time package.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+// Origin of the monotonic time of RealClock
+var monotonicOrigin = time.Now()
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Monotonic() time.Duration {
+	return time.Since(monotonicOrigin)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+/******************************************************************************/
+
+// A FakeClock is a Clock whose time only changes when told to. Its timers
+// fire when the time is advanced past their deadline.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	mono   time.Duration
+	timers []*fakeTimer // pending ones
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Duration // monotonic
+	c        chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to a time instant.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Monotonic() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mono
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schedule(t, d)
+	return t
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance moves the time forward, firing the timers which expire meanwhile.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.mono += d
+	c.fire()
+}
+
+// Jump moves the wall clock by a duration, forward or backward, as when it
+// is stepped by NTP or after the host resumes from suspend. The monotonic
+// time and the timers are unaffected.
+func (c *FakeClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// BlockUntil waits until at least `n` timers are pending, such as those of
+// goroutines expected to be waiting on the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// schedule arms a timer, c.mu being held.
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = c.mono + d
+	c.timers = append(c.timers, t)
+	c.fire()
+	c.cond.Broadcast()
+}
+
+// fire sends the current time to the expired timers in deadline order, c.mu
+// being held.
+func (c *FakeClock) fire() {
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline < c.timers[j].deadline
+	})
+	for len(c.timers) > 0 && c.timers[0].deadline <= c.mono {
+		select {
+		case c.timers[0].c <- c.now:
+		default:
+		}
+		c.timers = c.timers[1:]
+	}
+}
+
+// unschedule disarms a timer and tells whether it was pending, c.mu being
+// held.
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return pending
+}
@@ -0,0 +1,106 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+/******************************************************************************/
+
+// DefaultAccuracy is the default of `AccuracySec=`.
+const DefaultAccuracy = time.Minute
+
+// File holding the ID of the current boot
+var bootIDFile = "/proc/sys/kernel/random/boot_id"
+
+// BootID returns the ID of the current boot of the host, which changes at
+// each boot.
+func BootID() ([]byte, error) {
+	content, err := os.ReadFile(bootIDFile)
+	if err != nil {
+		return nil, fmt.Errorf("coalesce: %s", err)
+	}
+	return bytes.TrimSpace(content), nil
+}
+
+// Perturbation returns the phase in [0, 1min) at which the timers of a boot
+// wake up, derived from its ID as systemd does. An ID which is not a UUID,
+// such as a test seed, is hashed first.
+func Perturbation(bootID []byte) time.Duration {
+	id, err := hex.DecodeString(string(bytes.ReplaceAll(bootID, []byte("-"), nil)))
+	if err != nil || len(id) != 16 {
+		sum := sha256.Sum256(bootID)
+		id = sum[:16]
+	}
+	perturb := binary.LittleEndian.Uint64(id[:8]) ^ binary.LittleEndian.Uint64(id[8:])
+	return time.Duration(perturb%uint64(time.Minute/time.Microsecond)) * time.Microsecond
+}
+
+/******************************************************************************/
+
+// A Coalescer batches the elapses of several expressions into shared
+// wakeups, like `AccuracySec=` does for systemd timers: each elapse may be
+// delayed by up to Accuracy so that timers due at about the same time are
+// handled at once.
+type Coalescer struct {
+	// Accuracy is how late an elapse may be handled, none when zero.
+	Accuracy time.Duration
+	// Perturbation is the phase of wakeups within a minute, see Perturbation.
+	Perturbation time.Duration
+}
+
+// Next returns the first wakeup following `fromTime` and the indices of the
+// expressions due at it, or the zero time if none of them elapses anymore.
+func (c Coalescer) Next(exprs []*Expression, fromTime time.Time) (time.Time, []int) {
+	nexts := make([]time.Time, len(exprs))
+	var earliest, latest time.Time
+	for i, expr := range exprs {
+		nexts[i] = expr.Next(fromTime)
+		if nexts[i].IsZero() {
+			continue
+		}
+		if earliest.IsZero() || nexts[i].Before(earliest) {
+			earliest = nexts[i]
+		}
+		if deadline := nexts[i].Add(c.Accuracy); latest.IsZero() || deadline.Before(latest) {
+			latest = deadline
+		}
+	}
+	if earliest.IsZero() {
+		return time.Time{}, nil
+	}
+
+	wakeup := c.sleepBetween(earliest, latest)
+	var due []int
+	for i, next := range nexts {
+		if !next.IsZero() && !next.After(wakeup) {
+			due = append(due, i)
+		}
+	}
+	return wakeup, due
+}
+
+// Steps at which systemd aligns wakeups, from the preferred one
+var coalesceSteps = []time.Duration{time.Minute, 10 * time.Second, time.Second, 250 * time.Millisecond}
+
+// sleepBetween picks a wakeup in [a, b] as sd-event does: the same point of
+// every minute if possible, else of every 10s, 1s or 250ms, else b.
+func (c Coalescer) sleepBetween(a, b time.Time) time.Time {
+	for _, step := range coalesceSteps {
+		wakeup := b.Truncate(step).Add(c.Perturbation % step)
+		if !wakeup.Before(b) {
+			wakeup = wakeup.Add(-step)
+		}
+		if !wakeup.Before(a) {
+			return wakeup
+		}
+	}
+	return b
+}
@@ -0,0 +1,57 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"strings"
+)
+
+/******************************************************************************/
+
+// A LossyError is returned when an expression cannot be converted exactly
+// from one syntax to another. It names what the target syntax cannot express.
+type LossyError struct {
+	Syntax     string
+	Components []string
+}
+
+func (e *LossyError) Error() string {
+	return fmt.Sprintf("cannot convert exactly to %s syntax: %s", e.Syntax, strings.Join(e.Components, ", "))
+}
+
+/******************************************************************************/
+
+// ToSystemd converts a cron expression, as accepted by ParseCron, into a
+// systemd OnCalendar expression in normalized form.
+//
+// When the conversion cannot be exact, the closest systemd expression is
+// returned along with a *LossyError: `15W` becomes the 15th itself and
+// `Mon#1,Fri#3` the mondays and fridays of the first and third weeks.
+func ToSystemd(cronLine string) (string, error) {
+	expr, err := ParseCron(cronLine)
+	if err != nil {
+		return "", err
+	}
+	systemd, lossy := expr.systemdString()
+	if len(lossy) > 0 {
+		return systemd, &LossyError{Syntax: "systemd", Components: lossy}
+	}
+	return systemd, nil
+}
+
+/******************************************************************************/
+
+// ToCron converts an expression into a five-field Vixie cron expression.
+//
+// Seconds, years, time zones, days counted from the end of the month and the
+// other extensions cannot be written in such a line: when the conversion
+// cannot be exact, the closest cron expression is returned along with a
+// *LossyError.
+func ToCron(expr *Expression) (string, error) {
+	cron, lossy := expr.cronString()
+	if len(lossy) > 0 {
+		return cron, &LossyError{Syntax: "cron", Components: lossy}
+	}
+	return cron, nil
+}
@@ -0,0 +1,101 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"strings"
+)
+
+/******************************************************************************/
+
+var cronNormalizer = strings.NewReplacer(
+	"@yearly", "0 0 0 1 1 * *",
+	"@annually", "0 0 0 1 1 * *",
+	"@monthly", "0 0 0 1 * * *",
+	"@weekly", "0 0 0 * * 0 *",
+	"@daily", "0 0 0 * * * *",
+	"@hourly", "0 0 * * * * *",
+)
+
+/******************************************************************************/
+
+// MustParseCron is like ParseCron, but it will `panic` if a malformed cron
+// expression is supplied.
+func MustParseCron(cronLine string) *Expression {
+	expr, err := ParseCron(cronLine)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+/******************************************************************************/
+
+// ParseCron returns a new Expression pointer built from a classic cron
+// expression. An error is returned if a malformed cron expression is supplied.
+//
+// Five fields are read as Vixie cron: minute, hour, day of month, month and
+// day of week. Six fields add a trailing year and seven fields add a leading
+// second, as described at <https://github.com/gorhill/cronexpr#implementation>.
+// The `L`, `W` and `#` directives and the `@daily` style aliases are
+// supported. As with cron, when both the day of month and the day of week are
+// restricted, a day matching either of them is a hit.
+func ParseCron(cronLine string) (*Expression, error) {
+	var expr = Expression{
+		expression: cronLine,
+	}
+
+	cron := cronNormalizer.Replace(strings.TrimSpace(cronLine))
+	indices := fieldFinder.FindAllStringIndex(cron, -1)
+	fieldCount := len(indices)
+	if fieldCount < 5 {
+		return nil, fmt.Errorf("missing field(s)")
+	}
+	if fieldCount > 7 {
+		return nil, fmt.Errorf("too much field(s)")
+	}
+
+	fields := make([]string, 0, 7)
+	for _, index := range indices {
+		fields = append(fields, cron[index[0]:index[1]])
+	}
+	// second field (optional)
+	if fieldCount < 7 {
+		fields = append([]string{"0"}, fields...)
+	}
+	// year field (optional)
+	if len(fields) < 7 {
+		fields = append(fields, "*")
+	}
+
+	err := expr.secondFieldHandler(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.minuteFieldHandler(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.hourFieldHandler(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.domFieldHandler(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.monthFieldHandler(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.dowFieldHandler(fields[5])
+	if err != nil {
+		return nil, err
+	}
+	err = expr.yearFieldHandler(fields[6])
+	if err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
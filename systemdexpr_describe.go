@@ -0,0 +1,234 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+// Describe returns an English description of when the expression elapses,
+// such as "at 09:00:00 on Monday through Friday, on days 1–7 of every month".
+// The description is built from the parsed expression, so that two
+// expressions with the same elapses are described the same way.
+func Describe(expr *Expression) string {
+	return DescribeIn(expr, English)
+}
+
+// DescribeIn is like Describe, but describes the expression in the language
+// of a locale, such as German or Russian.
+func DescribeIn(expr *Expression, locale Locale) string {
+	at, repeated := describeTime(expr, locale)
+	parts := []string{at}
+	if days := describeDays(expr, locale); days != "" {
+		parts = append(parts, days)
+	} else if !repeated {
+		parts = append(parts, locale.Phrase(PhraseEveryDay))
+	}
+	if !isDefaultList(expr.yearList, yearDescriptor) {
+		parts = append(parts, locale.Phrase(PhraseInYears, describeList(expr.yearList, yearDescriptor.max, locale, strconv.Itoa)))
+	}
+	if expr.timeZone != nil {
+		parts = append(parts, locale.Phrase(PhraseTimeZone, expr.timeZone.String()))
+	}
+	return strings.Join(parts, " ")
+}
+
+/******************************************************************************/
+
+// describeTime also tells whether the expression elapses repeatedly in the
+// hours or minutes it describes.
+func describeTime(expr *Expression, locale Locale) (string, bool) {
+	// A few times of day are given as such
+	if count := len(expr.hourList) * len(expr.minuteList) * len(expr.secondList); count > 0 && count <= 4 {
+		times := make([]string, 0, count)
+		for _, h := range expr.hourList {
+			for _, m := range expr.minuteList {
+				for _, s := range expr.secondList {
+					times = append(times, fmt.Sprintf("%02d:%02d:%02d", h, m, s))
+				}
+			}
+		}
+		return locale.Phrase(PhraseAt, locale.Join(times)), false
+	}
+
+	fields := []struct {
+		list           []int
+		desc           fieldDescriptor
+		every, ofEvery Phrase
+		everyN, from   Phrase
+		at, of         Phrase
+	}{
+		{expr.secondList, secondDescriptor, PhraseEverySecond, PhraseEverySecond, PhraseEveryNSeconds, PhraseFromSecond, PhraseAtSeconds, PhraseAtSeconds},
+		{expr.minuteList, minuteDescriptor, PhraseEveryMinute, PhraseOfEveryMinute, PhraseEveryNMinutes, PhraseFromMinute, PhraseAtMinutes, PhraseOfMinutes},
+		{expr.hourList, hourDescriptor, PhraseEveryHour, PhraseOfEveryHour, PhraseEveryNHours, PhraseFromHour, PhraseAtHours, PhraseDuringHours},
+	}
+	var parts []string
+	previousEvery, repeated := false, false
+	for i, field := range fields {
+		first := len(parts) == 0
+		// `:00` is implied when nothing finer is described
+		if first && i < len(fields)-1 && len(field.list) == 1 && field.list[0] == 0 {
+			continue
+		}
+		segments := segmentList(field.list, field.desc.max)
+		switch {
+		case isDefaultList(field.list, field.desc):
+			if !previousEvery {
+				if first {
+					parts = append(parts, locale.Phrase(field.every))
+				} else {
+					parts = append(parts, locale.Phrase(field.ofEvery))
+				}
+			}
+			previousEvery, repeated = true, true
+		case len(segments) == 1 && segments[0].step > 1 && segments[0].open && segments[0].first == field.desc.min:
+			parts = append(parts, locale.Phrase(field.everyN, segments[0].step))
+			previousEvery, repeated = true, true
+		case len(segments) == 1 && segments[0].step > 1:
+			parts = append(parts, describeRepetition(segments[0], field.everyN, field.from, locale))
+			previousEvery, repeated = true, true
+		default:
+			phrase := field.at
+			if !first {
+				phrase = field.of
+			}
+			parts = append(parts, locale.Phrase(phrase, len(field.list), describeList(field.list, field.desc.max, locale, strconv.Itoa)))
+			previousEvery = false
+		}
+	}
+	return strings.Join(parts, " "), repeated
+}
+
+/******************************************************************************/
+
+// describeDays returns an empty string for every day of every month.
+func describeDays(expr *Expression, locale Locale) string {
+	months := ""
+	if !isDefaultList(expr.monthList, monthDescriptor) {
+		months = describeList(expr.monthList, -1, locale, func(v int) string {
+			return locale.Month(time.Month(v))
+		})
+	}
+
+	weekdays := ""
+	if expr.daysOfWeekRestricted {
+		weekdays = describeWeekdays(expr, locale)
+	}
+	if expr.daysOfMonthRestricted {
+		days := describeDaysOfMonth(expr, locale)
+		if months == "" {
+			days += " " + locale.Phrase(PhraseOfEveryMonth)
+		} else {
+			days += " " + locale.Phrase(PhraseOfMonths, months)
+		}
+		switch {
+		case weekdays == "":
+			return days
+		case expr.bothDaysRequired:
+			return locale.Phrase(PhraseBoth, weekdays, days)
+		default:
+			return locale.Phrase(PhraseEither, days, weekdays)
+		}
+	}
+	if months == "" {
+		return weekdays
+	}
+	if weekdays == "" {
+		weekdays = locale.Phrase(PhraseEveryDay)
+	}
+	return weekdays + " " + locale.Phrase(PhraseInMonths, months)
+}
+
+func describeWeekdays(expr *Expression, locale Locale) string {
+	var parts []string
+	// weeks start on monday
+	monday := make(map[int]bool)
+	for v := range expr.daysOfWeek {
+		monday[(v+6)%7] = true
+	}
+	if len(monday) > 0 {
+		parts = append(parts, describeList(toList(monday), -1, locale, func(v int) string {
+			return locale.Weekday(time.Weekday((v + 1) % 7))
+		}))
+	}
+	for _, v := range mapToList(expr.specificWeekDaysOfWeek) {
+		parts = append(parts, locale.Phrase(PhraseOnNthWeekday, v/7+1, locale.Ordinal(v/7+1), locale.Weekday(time.Weekday(v%7))))
+	}
+	for _, v := range mapToList(expr.lastWeekDaysOfWeek) {
+		parts = append(parts, locale.Phrase(PhraseOnLastWeekday, locale.Weekday(time.Weekday(v))))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return locale.Phrase(PhraseOnWeekdays, locale.Join(parts))
+}
+
+func describeDaysOfMonth(expr *Expression, locale Locale) string {
+	var parts []string
+	if days := mapToList(expr.daysOfMonth); len(days) > 0 {
+		if segments := segmentList(days, domDescriptor.max); len(segments) == 1 && segments[0].step > 1 {
+			parts = append(parts, describeRepetition(segments[0], PhraseEveryNDays, PhraseFromDay, locale))
+		} else {
+			parts = append(parts, locale.Phrase(PhraseOnDays, len(days), describeList(days, domDescriptor.max, locale, strconv.Itoa)))
+		}
+	}
+	for _, v := range mapToList(expr.workdaysOfMonth) {
+		parts = append(parts, locale.Phrase(PhraseOnNearestWorkday, v))
+	}
+
+	fromEnd := mapToList(expr.daysFromEndOfMonth)
+	if expr.lastDayOfMonth && !sortContains(fromEnd, 1) {
+		fromEnd = append([]int{1}, fromEnd...)
+	}
+	if segments := segmentList(fromEnd, -1); len(segments) == 1 && segments[0].first == 1 && segments[0].last > 1 && segments[0].step == 1 {
+		parts = append(parts, locale.Phrase(PhraseOnLastNDays, segments[0].last))
+	} else {
+		for _, v := range fromEnd {
+			if v == 1 {
+				parts = append(parts, locale.Phrase(PhraseOnLastDay))
+			} else {
+				parts = append(parts, locale.Phrase(PhraseOnNthLastDay, v, locale.Ordinal(v)))
+			}
+		}
+	}
+	if expr.lastWorkdayOfMonth {
+		parts = append(parts, locale.Phrase(PhraseOnLastWorkday))
+	}
+	return locale.Join(parts)
+}
+
+/******************************************************************************/
+
+// describeList names the values of a field, with ranges of at least three
+// values. Ranges of named values are spelled out when `max` is negative.
+func describeList(list []int, max int, locale Locale, name func(int) string) string {
+	var parts []string
+	for _, segment := range segmentList(list, max) {
+		switch {
+		case segment.step != 1:
+			for v := segment.first; v <= segment.last; v += segment.step {
+				parts = append(parts, name(v))
+			}
+		case segment.first == segment.last:
+			parts = append(parts, name(segment.first))
+		case max < 0:
+			parts = append(parts, locale.Phrase(PhraseThrough, name(segment.first), name(segment.last)))
+		default:
+			parts = append(parts, name(segment.first)+"–"+name(segment.last))
+		}
+	}
+	return locale.Join(parts)
+}
+
+func describeRepetition(segment listSegment, everyN, from Phrase, locale Locale) string {
+	first := strconv.Itoa(segment.first)
+	if !segment.open {
+		first = locale.Phrase(PhraseThrough, first, strconv.Itoa(segment.last))
+	}
+	return locale.Phrase(everyN, segment.step) + " " + locale.Phrase(from, first)
+}
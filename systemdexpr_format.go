@@ -0,0 +1,390 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/******************************************************************************/
+
+// A listSegment is a run of values found in a field: `first`, or
+// `first..last` when `step` is 1, or every `step` from `first` to `last`.
+type listSegment struct {
+	first, last, step int
+	open              bool // repeats up to the end of the field
+}
+
+// segmentList splits a sorted list of values into values, ranges of at least
+// three values and repetitions. `max` is the largest value of the field, so
+// that repetitions running up to it can be written without their end.
+func segmentList(list []int, max int) []listSegment {
+	segments := make([]listSegment, 0, len(list))
+	// A single repetition covering the whole list
+	if len(list) >= 3 {
+		step := list[1] - list[0]
+		progression := step > 1
+		for i := 2; progression && i < len(list); i++ {
+			progression = list[i]-list[i-1] == step
+		}
+		if progression {
+			last := list[len(list)-1]
+			return append(segments, listSegment{first: list[0], last: last, step: step, open: last+step > max})
+		}
+	}
+	for i := 0; i < len(list); {
+		j := i
+		for j+1 < len(list) && list[j+1] == list[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			segments = append(segments, listSegment{first: list[i], last: list[j], step: 1})
+			i = j + 1
+		} else {
+			segments = append(segments, listSegment{first: list[i], last: list[i], step: 1})
+			i++
+		}
+	}
+	return segments
+}
+
+// Key of the directives of the days counted from the end of the month
+const fromEndDirectivesKey = "days-from-end"
+
+// setDirectives records the directives of a field as segments, sorted and
+// without duplicates as systemd normalizes them. `*` records nothing.
+func (expr *Expression) setDirectives(name string, directives []*cronDirective) {
+	if expr.directives == nil {
+		expr.directives = make(map[string][]listSegment)
+	}
+	segments := make([]listSegment, 0, len(directives))
+	for _, directive := range directives {
+		switch directive.kind {
+		case one:
+			segments = append(segments, listSegment{first: directive.first, last: directive.first, step: 1})
+		case span:
+			segments = append(segments, listSegment{first: directive.first, last: directive.last, step: directive.step, open: directive.open})
+		case all:
+			delete(expr.directives, name)
+			return
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		a, b := segments[i], segments[j]
+		if a.first != b.first {
+			return a.first < b.first
+		}
+		if a.last != b.last {
+			return a.last < b.last
+		}
+		return a.step < b.step
+	})
+	unique := segments[:0]
+	for i, segment := range segments {
+		if i == 0 || segment != segments[i-1] {
+			unique = append(unique, segment)
+		}
+	}
+	expr.directives[name] = unique
+}
+
+// fieldSegments returns the directives recorded for a field if they still
+// give its values, else the values one by one. `fromEnd` directives walk
+// from their start towards the end of the month, as `~07/1` or `~05..01/3`.
+func (expr *Expression) fieldSegments(name string, list []int, fromEnd bool) []listSegment {
+	segments := expr.directives[name]
+	values := make(map[int]bool)
+	for _, segment := range segments {
+		last, step := segment.last, segment.step
+		if fromEnd && (segment.open || segment.first > segment.last) {
+			last, step = 1, -step
+			if !segment.open {
+				last = segment.last
+			}
+		}
+		for v := segment.first; (step > 0 && v <= last) || (step < 0 && v >= last); v += step {
+			values[v] = true
+		}
+	}
+	if len(segments) == 0 || len(values) != len(list) || !containsAll(list, values) {
+		segments = make([]listSegment, len(list))
+		for i, v := range list {
+			segments[i] = listSegment{first: v, last: v, step: 1}
+		}
+	}
+	return segments
+}
+
+func containsAll(list []int, values map[int]bool) bool {
+	for _, v := range list {
+		if !values[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func isDefaultList(list []int, desc fieldDescriptor) bool {
+	if len(list) != len(desc.defaultList) {
+		return false
+	}
+	for i := range list {
+		if list[i] != desc.defaultList[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func mapToList(set map[int]bool) []int {
+	if len(set) == 0 {
+		return nil
+	}
+	return toList(set)
+}
+
+/******************************************************************************/
+
+var systemdWeekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// String returns the normalized systemd form of the expression, such as
+// `Mon..Fri *-*-* 09:00:00`. Cron directives which systemd cannot express
+// are left out, see ToSystemd.
+func (expr *Expression) String() string {
+	s, _ := expr.systemdString()
+	return s
+}
+
+// systemdString renders the expression in normalized systemd syntax, along
+// with the components which systemd cannot express.
+func (expr *Expression) systemdString() (string, []string) {
+	var lossy []string
+	var b strings.Builder
+
+	// weekdays
+	weekdays := mapToList(expr.daysOfWeek)
+	dom := "*"
+	if expr.daysOfWeekRestricted {
+		// `Fri#3` and `FriL` are the third and last weeks of the month
+		weeks := make(map[int]bool)
+		nthWeekdays := make(map[int]bool)
+		for v := range expr.specificWeekDaysOfWeek {
+			weeks[v/7] = true
+			nthWeekdays[v%7] = true
+		}
+		for v := range expr.lastWeekDaysOfWeek {
+			weeks[-1] = true
+			nthWeekdays[v] = true
+		}
+		weekdays = append(weekdays, mapToList(nthWeekdays)...)
+		switch {
+		case len(weeks) == 0:
+		case len(expr.daysOfWeek) > 0 || expr.daysOfMonthRestricted || (weeks[-1] && len(weeks) > 1):
+			// any week of the month is the closest
+			lossy = append(lossy, "weekday of a given week")
+		case weeks[-1]:
+			dom = "~07..01"
+		default:
+			// every weekday is matched in every week, such as `Mon#1,Fri#3`
+			// giving `Mon,Fri *-*-01..07,15..21`
+			if len(expr.specificWeekDaysOfWeek) != len(weeks)*len(nthWeekdays) {
+				lossy = append(lossy, "weekday of a given week")
+			}
+			ranges := make([]string, 0, len(weeks))
+			for _, week := range mapToList(weeks) {
+				last := 7*week + 7
+				if last > domDescriptor.max {
+					last = domDescriptor.max
+				}
+				ranges = append(ranges, fmt.Sprintf("%02d..%02d", 7*week+1, last))
+			}
+			dom = strings.Join(ranges, ",")
+		}
+		b.WriteString(formatSystemdWeekdays(weekdays))
+		b.WriteByte(' ')
+	}
+
+	// date
+	if isDefaultList(expr.yearList, yearDescriptor) {
+		b.WriteString("*")
+	} else {
+		b.WriteString(expr.formatSystemdList(expr.yearList, yearDescriptor, 4))
+	}
+	b.WriteByte('-')
+	b.WriteString(expr.formatSystemdList(expr.monthList, monthDescriptor, 2))
+	if expr.daysOfMonthRestricted {
+		if expr.daysOfWeekRestricted && !expr.bothDaysRequired {
+			lossy = append(lossy, "day of month or day of week")
+		}
+		// the nearest workday to a day is approximated by the day itself
+		if len(expr.workdaysOfMonth) > 0 || expr.lastWorkdayOfMonth {
+			lossy = append(lossy, "nearest workday")
+		}
+		fromEnd := mapToList(expr.daysFromEndOfMonth)
+		if (expr.lastDayOfMonth || expr.lastWorkdayOfMonth) && !sortContains(fromEnd, 1) {
+			fromEnd = append([]int{1}, fromEnd...)
+		}
+		daySet := make(map[int]bool)
+		for v := range expr.daysOfMonth {
+			daySet[v] = true
+		}
+		for v := range expr.workdaysOfMonth {
+			daySet[v] = true
+		}
+		days := mapToList(daySet)
+		switch {
+		case len(fromEnd) > 0 && len(days) > 0:
+			lossy = append(lossy, "days both from the start and the end of month")
+			fallthrough
+		case len(days) > 0:
+			dom = expr.formatSystemdList(days, domDescriptor, 2)
+		case len(fromEnd) > 0:
+			dom = expr.formatSystemdFromEnd(fromEnd)
+		}
+	}
+	if strings.HasPrefix(dom, "~") {
+		b.WriteString(dom)
+	} else {
+		b.WriteByte('-')
+		b.WriteString(dom)
+	}
+
+	// time
+	b.WriteByte(' ')
+	b.WriteString(expr.formatSystemdList(expr.hourList, hourDescriptor, 2))
+	b.WriteByte(':')
+	b.WriteString(expr.formatSystemdList(expr.minuteList, minuteDescriptor, 2))
+	b.WriteByte(':')
+	b.WriteString(expr.formatSystemdList(expr.secondList, secondDescriptor, 2))
+
+	// time zone
+	if expr.timeZone != nil {
+		b.WriteByte(' ')
+		b.WriteString(expr.timeZone.String())
+	}
+	return b.String(), lossy
+}
+
+func formatSystemdWeekdays(weekdays []int) string {
+	// systemd weeks start on monday
+	set := make(map[int]bool)
+	for _, v := range weekdays {
+		set[(v+6)%7] = true
+	}
+	parts := make([]string, 0, len(set))
+	for _, segment := range segmentList(toList(set), -1) {
+		if segment.step != 1 {
+			// weekdays are never written as a repetition
+			for v := segment.first; v <= segment.last; v += segment.step {
+				parts = append(parts, systemdWeekdayNames[(v+1)%7])
+			}
+		} else if segment.first == segment.last {
+			parts = append(parts, systemdWeekdayNames[(segment.first+1)%7])
+		} else {
+			parts = append(parts, systemdWeekdayNames[(segment.first+1)%7]+".."+systemdWeekdayNames[(segment.last+1)%7])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (expr *Expression) formatSystemdList(list []int, desc fieldDescriptor, width int) string {
+	if isDefaultList(list, desc) {
+		return "*"
+	}
+	return formatSegments(expr.fieldSegments(desc.name, list, false), width)
+}
+
+// formatSystemdFromEnd writes days counted from the end of the month.
+func (expr *Expression) formatSystemdFromEnd(fromEnd []int) string {
+	return "~" + formatSegments(expr.fieldSegments(fromEndDirectivesKey, fromEnd, true), 2)
+}
+
+func formatSegments(segments []listSegment, width int) string {
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		switch {
+		case segment.open:
+			parts = append(parts, fmt.Sprintf("%0*d/%d", width, segment.first, segment.step))
+		case segment.step != 1:
+			parts = append(parts, fmt.Sprintf("%0*d..%0*d/%d", width, segment.first, width, segment.last, segment.step))
+		case segment.first == segment.last:
+			parts = append(parts, fmt.Sprintf("%0*d", width, segment.first))
+		default:
+			parts = append(parts, fmt.Sprintf("%0*d..%0*d", width, segment.first, width, segment.last))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+/******************************************************************************/
+
+// cronString renders the expression as a five-field Vixie cron line, along
+// with the components which such a line cannot express.
+func (expr *Expression) cronString() (string, []string) {
+	var lossy []string
+
+	if !(len(expr.secondList) == 1 && expr.secondList[0] == 0) {
+		lossy = append(lossy, "seconds")
+	}
+	if !isDefaultList(expr.yearList, yearDescriptor) {
+		lossy = append(lossy, "years")
+	}
+	if expr.timeZone != nil {
+		lossy = append(lossy, "time zone")
+	}
+
+	dom := "*"
+	if expr.daysOfMonthRestricted {
+		if expr.lastDayOfMonth || len(expr.daysFromEndOfMonth) > 0 {
+			lossy = append(lossy, "days counted from the end of month")
+		}
+		if len(expr.workdaysOfMonth) > 0 || expr.lastWorkdayOfMonth {
+			lossy = append(lossy, "nearest workday")
+		}
+		if days := mapToList(expr.daysOfMonth); len(days) > 0 {
+			dom = formatCronList(days, domDescriptor)
+		}
+	}
+	dow := "*"
+	if expr.daysOfWeekRestricted {
+		if len(expr.specificWeekDaysOfWeek) > 0 || len(expr.lastWeekDaysOfWeek) > 0 {
+			lossy = append(lossy, "weekday of a given week")
+		}
+		if days := mapToList(expr.daysOfWeek); len(days) > 0 {
+			dow = formatCronList(days, dowDescriptor)
+		}
+	}
+	if expr.daysOfMonthRestricted && expr.daysOfWeekRestricted && expr.bothDaysRequired {
+		lossy = append(lossy, "day of month and day of week")
+	}
+
+	return strings.Join([]string{
+		formatCronList(expr.minuteList, minuteDescriptor),
+		formatCronList(expr.hourList, hourDescriptor),
+		dom,
+		formatCronList(expr.monthList, monthDescriptor),
+		dow,
+	}, " "), lossy
+}
+
+func formatCronList(list []int, desc fieldDescriptor) string {
+	if isDefaultList(list, desc) {
+		return "*"
+	}
+	parts := make([]string, 0, len(list))
+	for _, segment := range segmentList(list, desc.max) {
+		switch {
+		case segment.step != 1 && segment.open && segment.first == desc.min:
+			parts = append(parts, fmt.Sprintf("*/%d", segment.step))
+		case segment.step != 1:
+			parts = append(parts, fmt.Sprintf("%d-%d/%d", segment.first, segment.last, segment.step))
+		case segment.first == segment.last:
+			parts = append(parts, fmt.Sprintf("%d", segment.first))
+		default:
+			parts = append(parts, fmt.Sprintf("%d-%d", segment.first, segment.last))
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,140 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+// Most events expanded for a single entry by WriteCalendar
+const calendarMaxEvents = 1000
+
+const calendarTimeLayout = "20060102T150405Z"
+
+var calendarTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+/******************************************************************************/
+
+// A CalendarEntry is a labelled expression written by WriteCalendar. Events
+// last for `Duration`, which may be zero.
+type CalendarEntry struct {
+	Label      string
+	Expression *Expression
+	Duration   time.Duration
+}
+
+/******************************************************************************/
+
+// WriteCalendar writes an iCalendar file as defined by RFC 5545, holding the
+// elapses of each entry from `from` up to and including `to`.
+//
+// When the elapses of an entry are evaluated in UTC and its expression can be
+// represented as a recurrence rule, a single recurring event is written.
+// Otherwise every elapse is written as its own event, and an error is returned
+// if there are more than 1000 of them in the window, in which case nothing is
+// written. The window start is used as DTSTAMP so that the same input always
+// gives the same file.
+func WriteCalendar(w io.Writer, from, to time.Time, entries ...CalendarEntry) error {
+	// the calendar is built in memory so that an error leaves w untouched
+	var cw calendarWriter
+	stamp := from.UTC().Format(calendarTimeLayout)
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//systemdexpr//systemdexpr//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	for i, entry := range entries {
+		summary := entry.Label
+		if summary == "" {
+			summary = entry.Expression.expression
+		}
+		event := func(start time.Time, rrule string) {
+			cw.line("BEGIN:VEVENT")
+			cw.line(fmt.Sprintf("UID:%d-%d@systemdexpr", start.Unix(), i))
+			cw.line("DTSTAMP:" + stamp)
+			cw.line("DTSTART:" + start.UTC().Format(calendarTimeLayout))
+			if entry.Duration > 0 {
+				cw.line("DTEND:" + start.Add(entry.Duration).UTC().Format(calendarTimeLayout))
+			}
+			if rrule != "" {
+				cw.line("RRULE:" + rrule)
+			}
+			cw.line("SUMMARY:" + calendarTextEscaper.Replace(summary))
+			cw.line("DESCRIPTION:" + calendarTextEscaper.Replace(entry.Expression.expression))
+			cw.line("END:VEVENT")
+		}
+
+		first := entry.Expression.Next(from.Add(-time.Nanosecond))
+		if first.IsZero() || first.After(to) {
+			continue
+		}
+
+		// A single recurring event
+		loc := from.Location()
+		if entry.Expression.timeZone != nil {
+			loc = entry.Expression.timeZone
+		}
+		if loc.String() == "UTC" {
+			if parts, lastYear, err := entry.Expression.rruleParts(); err == nil {
+				until := to.UTC()
+				if lastYear != 0 {
+					if last := time.Date(lastYear, time.December, 31, 23, 59, 59, 0, time.UTC); last.Before(until) {
+						until = last
+					}
+				}
+				parts = append(parts, "UNTIL="+until.Format(calendarTimeLayout))
+				event(first, strings.Join(parts, ";"))
+				continue
+			}
+		}
+
+		// One event per elapse
+		elapses := entry.Expression.NextN(from.Add(-time.Nanosecond), calendarMaxEvents+1)
+		for j, elapse := range elapses {
+			if elapse.After(to) {
+				break
+			}
+			if j == calendarMaxEvents {
+				return fmt.Errorf("more than %d elapses of '%s' in calendar window", calendarMaxEvents, summary)
+			}
+			event(elapse, "")
+		}
+	}
+	cw.line("END:VCALENDAR")
+	_, err := io.WriteString(w, cw.b.String())
+	return err
+}
+
+/******************************************************************************/
+
+type calendarWriter struct {
+	b strings.Builder
+}
+
+// line writes a content line, folded at 75 octets without splitting UTF-8
+// sequences.
+func (cw *calendarWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		cw.b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// the leading space of a continuation line counts
+		limit = 74
+	}
+	cw.b.WriteString(s + "\r\n")
+}
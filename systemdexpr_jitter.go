@@ -0,0 +1,90 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/******************************************************************************/
+
+// Files holding the machine ID, by order of preference
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// MachineID returns the machine ID of the host, as found in
+// `/etc/machine-id`.
+func MachineID() ([]byte, error) {
+	for _, path := range machineIDFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := bytes.TrimSpace(content); len(id) > 0 {
+			return id, nil
+		}
+	}
+	return nil, fmt.Errorf("jitter: no machine ID found in %v", machineIDFiles)
+}
+
+/******************************************************************************/
+
+// A Jitter delays each elapse of an expression by up to a maximum, like
+// `RandomizedDelaySec=` does for systemd timers.
+type Jitter struct {
+	expr     *Expression
+	maxDelay time.Duration
+	fixed    bool
+	delay    time.Duration // when fixed
+}
+
+// NewJitter returns a Jitter delaying each elapse by a new random duration
+// in [0, maxDelay).
+func NewJitter(expr *Expression, maxDelay time.Duration) *Jitter {
+	return &Jitter{expr: expr, maxDelay: maxDelay}
+}
+
+// NewFixedJitter returns a Jitter delaying every elapse by the same duration
+// in [0, maxDelay), like `FixedRandomDelay=true` does. The delay is derived
+// from a seed, such as the MachineID, and the name of the unit, so that it
+// is stable across restarts but differs across machines and units.
+func NewFixedJitter(expr *Expression, maxDelay time.Duration, seed []byte, unit string) *Jitter {
+	return &Jitter{expr: expr, maxDelay: maxDelay, fixed: true, delay: FixedDelay(maxDelay, seed, unit)}
+}
+
+// FixedDelay returns the delay in [0, maxDelay) of a NewFixedJitter.
+func FixedDelay(maxDelay time.Duration, seed []byte, unit string) time.Duration {
+	if maxDelay <= 0 {
+		return 0
+	}
+	h := sha256.New()
+	h.Write(seed)
+	h.Write([]byte{0})
+	h.Write([]byte(unit))
+	sum := h.Sum(nil)
+	return time.Duration(binary.BigEndian.Uint64(sum[:8]) % uint64(maxDelay))
+}
+
+// Next returns the closest delayed elapse following `fromTime`, or the zero
+// time if there is none.
+func (j *Jitter) Next(fromTime time.Time) time.Time {
+	if j.fixed {
+		// an elapse preceding fromTime may be delayed past it
+		next := j.expr.Next(fromTime.Add(-j.delay))
+		if next.IsZero() {
+			return next
+		}
+		return next.Add(j.delay)
+	}
+
+	next := j.expr.Next(fromTime)
+	if next.IsZero() || j.maxDelay <= 0 {
+		return next
+	}
+	return next.Add(time.Duration(rand.Int63n(int64(j.maxDelay))))
+}
@@ -0,0 +1,229 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+// A Phrase is a piece of sentence which a Locale translates for DescribeIn.
+// The English text of each phrase is given along with the arguments it takes.
+type Phrase uint8
+
+const (
+	PhraseAt               Phrase = iota // "at %s": times
+	PhraseEverySecond                    // "every second"
+	PhraseEveryMinute                    // "every minute"
+	PhraseEveryHour                      // "every hour"
+	PhraseEveryDay                       // "every day"
+	PhraseOfEveryMinute                  // "of every minute"
+	PhraseOfEveryHour                    // "of every hour"
+	PhraseOfEveryMonth                   // "of every month"
+	PhraseEveryNSeconds                  // "every %d seconds": count
+	PhraseEveryNMinutes                  // "every %d minutes": count
+	PhraseEveryNHours                    // "every %d hours": count
+	PhraseEveryNDays                     // "every %d days": count
+	PhraseFromSecond                     // "from second %s": value
+	PhraseFromMinute                     // "from minute %s": value
+	PhraseFromHour                       // "from hour %s": value
+	PhraseFromDay                        // "from day %s": value
+	PhraseThrough                        // "%s through %s": first and last named values
+	PhraseAtSeconds                      // "at seconds %[2]s": count and values
+	PhraseAtMinutes                      // "at minutes %[2]s": count and values
+	PhraseOfMinutes                      // "of minutes %[2]s": count and values
+	PhraseAtHours                        // "at hours %[2]s": count and values
+	PhraseDuringHours                    // "during hours %[2]s": count and values
+	PhraseOnWeekdays                     // "on %s": weekdays, nth and last weekdays
+	PhraseOnNthWeekday                   // "the %[2]s %[3]s": number, ordinal and weekday
+	PhraseOnLastWeekday                  // "the last %s": weekday
+	PhraseOnDays                         // "on days %[2]s": count and values
+	PhraseOnLastDay                      // "on the last day"
+	PhraseOnLastNDays                    // "on the last %d days": count
+	PhraseOnNthLastDay                   // "on the %[2]s to last day": number and ordinal
+	PhraseOnNearestWorkday               // "on the workday nearest day %d": day
+	PhraseOnLastWorkday                  // "on the last workday"
+	PhraseOfMonths                       // "of %s": months
+	PhraseInMonths                       // "in %s": months
+	PhraseInYears                        // "in %s": years
+	PhraseEither                         // "%s or %s": days of month and days of week
+	PhraseBoth                           // "%s, %s": days of week and days of month
+	PhraseTimeZone                       // "(%s)": time zone
+	phraseCount
+)
+
+/******************************************************************************/
+
+// A Locale translates the descriptions built by DescribeIn.
+type Locale interface {
+	// Weekday returns the name of a weekday as used in descriptions.
+	Weekday(d time.Weekday) string
+	// Month returns the name of a month as used in descriptions.
+	Month(m time.Month) string
+	// Ordinal spells `n` as an ordinal number, "third".
+	Ordinal(n int) string
+	// Join joins a list of items, "a, b and c".
+	Join(items []string) string
+	// Phrase fills a piece of sentence with its arguments. When a phrase
+	// takes a count as first argument, it should agree with it.
+	Phrase(p Phrase, args ...interface{}) string
+}
+
+// A NameParser is a Locale whose weekday and month names can be read by
+// ParseWithOptions.
+type NameParser interface {
+	// ParseName returns the English abbreviation, such as `mon` or `jan`, of
+	// a lower case weekday or month name or abbreviation.
+	ParseName(name string) (string, bool)
+}
+
+/******************************************************************************/
+
+// tableLocale is a Locale built from tables of names and phrases.
+type tableLocale struct {
+	weekdays [7]string
+	months   [13]string
+	ordinals []string
+	ordinal  string // format of ordinals missing from `ordinals`
+	and      string
+	// Each phrase has a form per plural category of its first argument
+	phrases [phraseCount][]string
+	plural  func(n int) int
+	names   map[string]string
+}
+
+func (l *tableLocale) Weekday(d time.Weekday) string {
+	return l.weekdays[d]
+}
+
+func (l *tableLocale) Month(m time.Month) string {
+	return l.months[m]
+}
+
+func (l *tableLocale) Ordinal(n int) string {
+	if n > 0 && n < len(l.ordinals) {
+		return l.ordinals[n]
+	}
+	return fmt.Sprintf(l.ordinal, n)
+}
+
+func (l *tableLocale) Join(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + l.and + " " + items[len(items)-1]
+}
+
+func (l *tableLocale) Phrase(p Phrase, args ...interface{}) string {
+	forms := l.phrases[p]
+	form := forms[0]
+	if len(forms) > 1 && len(args) > 0 {
+		if n, ok := args[0].(int); ok {
+			form = forms[l.plural(n)]
+		}
+	}
+	return fmt.Sprintf(form, args...)
+}
+
+func (l *tableLocale) ParseName(name string) (string, bool) {
+	english, found := l.names[name]
+	return english, found
+}
+
+func pluralOther(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+/******************************************************************************/
+
+// English is the Locale used by Describe.
+var English Locale = &tableLocale{
+	weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	months:   [13]string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	ordinals: []string{"", "first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"},
+	ordinal:  "%dth",
+	and:      "and",
+	plural:   pluralOther,
+	phrases: [phraseCount][]string{
+		PhraseAt:               {"at %s"},
+		PhraseEverySecond:      {"every second"},
+		PhraseEveryMinute:      {"every minute"},
+		PhraseEveryHour:        {"every hour"},
+		PhraseEveryDay:         {"every day"},
+		PhraseOfEveryMinute:    {"of every minute"},
+		PhraseOfEveryHour:      {"of every hour"},
+		PhraseOfEveryMonth:     {"of every month"},
+		PhraseEveryNSeconds:    {"every %d second", "every %d seconds"},
+		PhraseEveryNMinutes:    {"every %d minute", "every %d minutes"},
+		PhraseEveryNHours:      {"every %d hour", "every %d hours"},
+		PhraseEveryNDays:       {"every %d day", "every %d days"},
+		PhraseFromSecond:       {"from second %s"},
+		PhraseFromMinute:       {"from minute %s"},
+		PhraseFromHour:         {"from hour %s"},
+		PhraseFromDay:          {"from day %s"},
+		PhraseThrough:          {"%s through %s"},
+		PhraseAtSeconds:        {"at second %[2]s", "at seconds %[2]s"},
+		PhraseAtMinutes:        {"at minute %[2]s", "at minutes %[2]s"},
+		PhraseOfMinutes:        {"of minute %[2]s", "of minutes %[2]s"},
+		PhraseAtHours:          {"at hour %[2]s", "at hours %[2]s"},
+		PhraseDuringHours:      {"during hour %[2]s", "during hours %[2]s"},
+		PhraseOnWeekdays:       {"on %s"},
+		PhraseOnNthWeekday:     {"the %[2]s %[3]s"},
+		PhraseOnLastWeekday:    {"the last %s"},
+		PhraseOnDays:           {"on day %[2]s", "on days %[2]s"},
+		PhraseOnLastDay:        {"on the last day"},
+		PhraseOnLastNDays:      {"on the last day", "on the last %d days"},
+		PhraseOnNthLastDay:     {"on the %[2]s to last day"},
+		PhraseOnNearestWorkday: {"on the workday nearest day %d"},
+		PhraseOnLastWorkday:    {"on the last workday"},
+		PhraseOfMonths:         {"of %s"},
+		PhraseInMonths:         {"in %s"},
+		PhraseInYears:          {"in %s"},
+		PhraseEither:           {"%s or %s"},
+		PhraseBoth:             {"%s, %s"},
+		PhraseTimeZone:         {"(%s)"},
+	},
+}
+
+/******************************************************************************/
+
+var (
+	localeNameFinder = regexp.MustCompile(`\p{L}+`)
+	// Region/City zone names, such as `America/Santo_Domingo`
+	zoneNameFinder = regexp.MustCompile(`^\p{L}.*/`)
+)
+
+// translateNames replaces the weekday and month names of a locale with their
+// English abbreviation, in the weekday and date fields of an expression: the
+// time zone, which follows the time or is a `Region/City` name, is left
+// alone.
+func translateNames(s string, parser NameParser) string {
+	var b strings.Builder
+	last := 0
+	for _, index := range fieldFinder.FindAllStringIndex(s, -1) {
+		field := s[index[0]:index[1]]
+		if strings.ContainsRune(field, ':') || zoneNameFinder.MatchString(field) {
+			break
+		}
+		b.WriteString(s[last:index[0]])
+		b.WriteString(localeNameFinder.ReplaceAllStringFunc(field, func(word string) string {
+			if english, found := parser.ParseName(strings.ToLower(word)); found {
+				return english
+			}
+			return word
+		}))
+		last = index[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
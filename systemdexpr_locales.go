@@ -0,0 +1,227 @@
+package systemdexpr
+
+/******************************************************************************/
+
+// German, Russian and Spanish locales, which can also be used to parse names
+// of weekdays and months in their language.
+var (
+	German Locale = &tableLocale{
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		months:   [13]string{"", "Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		// as used after `am`
+		ordinals: []string{"", "ersten", "zweiten", "dritten", "vierten", "fünften", "sechsten", "siebten", "achten", "neunten", "zehnten"},
+		ordinal:  "%d.",
+		and:      "und",
+		plural:   pluralOther,
+		phrases: [phraseCount][]string{
+			PhraseAt:               {"um %s"},
+			PhraseEverySecond:      {"jede Sekunde"},
+			PhraseEveryMinute:      {"jede Minute"},
+			PhraseEveryHour:        {"jede Stunde"},
+			PhraseEveryDay:         {"jeden Tag"},
+			PhraseOfEveryMinute:    {"jeder Minute"},
+			PhraseOfEveryHour:      {"jeder Stunde"},
+			PhraseOfEveryMonth:     {"jedes Monats"},
+			PhraseEveryNSeconds:    {"jede Sekunde", "alle %d Sekunden"},
+			PhraseEveryNMinutes:    {"jede Minute", "alle %d Minuten"},
+			PhraseEveryNHours:      {"jede Stunde", "alle %d Stunden"},
+			PhraseEveryNDays:       {"jeden Tag", "alle %d Tage"},
+			PhraseFromSecond:       {"ab Sekunde %s"},
+			PhraseFromMinute:       {"ab Minute %s"},
+			PhraseFromHour:         {"ab Stunde %s"},
+			PhraseFromDay:          {"ab Tag %s"},
+			PhraseThrough:          {"%s bis %s"},
+			PhraseAtSeconds:        {"in Sekunde %[2]s", "in den Sekunden %[2]s"},
+			PhraseAtMinutes:        {"in Minute %[2]s", "in den Minuten %[2]s"},
+			PhraseOfMinutes:        {"der Minute %[2]s", "der Minuten %[2]s"},
+			PhraseAtHours:          {"in Stunde %[2]s", "in den Stunden %[2]s"},
+			PhraseDuringHours:      {"während Stunde %[2]s", "während der Stunden %[2]s"},
+			PhraseOnWeekdays:       {"am %s"},
+			PhraseOnNthWeekday:     {"%[2]s %[3]s"},
+			PhraseOnLastWeekday:    {"letzten %s"},
+			PhraseOnDays:           {"am Tag %[2]s", "an den Tagen %[2]s"},
+			PhraseOnLastDay:        {"am letzten Tag"},
+			PhraseOnLastNDays:      {"am letzten Tag", "an den letzten %d Tagen"},
+			PhraseOnNthLastDay:     {"am %[1]d.-letzten Tag"},
+			PhraseOnNearestWorkday: {"am nächstgelegenen Werktag zum Tag %d"},
+			PhraseOnLastWorkday:    {"am letzten Werktag"},
+			PhraseOfMonths:         {"im %s"},
+			PhraseInMonths:         {"im %s"},
+			PhraseInYears:          {"im Jahr %s"},
+			PhraseEither:           {"%s oder %s"},
+			PhraseBoth:             {"%s, %s"},
+			PhraseTimeZone:         {"(%s)"},
+		},
+		names: map[string]string{
+			"montag": "mon", "mo": "mon",
+			"dienstag": "tue", "di": "tue",
+			"mittwoch": "wed", "mi": "wed",
+			"donnerstag": "thu", "do": "thu",
+			"freitag": "fri", "fr": "fri",
+			"samstag": "sat", "sonnabend": "sat", "sa": "sat",
+			"sonntag": "sun", "so": "sun",
+			"januar": "jan", "jän": "jan", "jänner": "jan",
+			"februar": "feb",
+			"märz":    "mar", "mär": "mar",
+			"mai":  "may",
+			"juni": "jun",
+			"juli": "jul",
+			"okt":  "oct", "oktober": "oct",
+			"dez": "dec", "dezember": "dec",
+		},
+	}
+
+	// Russian weekday names are in the accusative and month names in the
+	// genitive, as used after `в` and a day.
+	Russian Locale = &tableLocale{
+		weekdays: [7]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"},
+		months:   [13]string{"", "января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		ordinal:  "%d-й",
+		and:      "и",
+		plural:   pluralRussian,
+		phrases: [phraseCount][]string{
+			PhraseAt:               {"в %s"},
+			PhraseEverySecond:      {"каждую секунду"},
+			PhraseEveryMinute:      {"каждую минуту"},
+			PhraseEveryHour:        {"каждый час"},
+			PhraseEveryDay:         {"каждый день"},
+			PhraseOfEveryMinute:    {"каждой минуты"},
+			PhraseOfEveryHour:      {"каждого часа"},
+			PhraseOfEveryMonth:     {"каждого месяца"},
+			PhraseEveryNSeconds:    {"каждую %d секунду", "каждые %d секунды", "каждые %d секунд"},
+			PhraseEveryNMinutes:    {"каждую %d минуту", "каждые %d минуты", "каждые %d минут"},
+			PhraseEveryNHours:      {"каждый %d час", "каждые %d часа", "каждые %d часов"},
+			PhraseEveryNDays:       {"каждый %d день", "каждые %d дня", "каждые %d дней"},
+			PhraseFromSecond:       {"начиная с секунды %s"},
+			PhraseFromMinute:       {"начиная с минуты %s"},
+			PhraseFromHour:         {"начиная с часа %s"},
+			PhraseFromDay:          {"начиная с дня %s"},
+			PhraseThrough:          {"%s–%s"},
+			PhraseAtSeconds:        {"на секунде %[2]s", "на секундах %[2]s", "на секундах %[2]s"},
+			PhraseAtMinutes:        {"на минуте %[2]s", "на минутах %[2]s", "на минутах %[2]s"},
+			PhraseOfMinutes:        {"минуты %[2]s", "минут %[2]s", "минут %[2]s"},
+			PhraseAtHours:          {"в час %[2]s", "в часы %[2]s", "в часы %[2]s"},
+			PhraseDuringHours:      {"в течение часа %[2]s", "в течение часов %[2]s", "в течение часов %[2]s"},
+			PhraseOnWeekdays:       {"в %s"},
+			PhraseOnNthWeekday:     {"%[3]s %[1]d-й недели"},
+			PhraseOnLastWeekday:    {"%s последней недели"},
+			PhraseOnDays:           {"в день %[2]s", "в дни %[2]s", "в дни %[2]s"},
+			PhraseOnLastDay:        {"в последний день"},
+			PhraseOnLastNDays:      {"в последний %d день", "в последние %d дня", "в последние %d дней"},
+			PhraseOnNthLastDay:     {"в %[1]d-й день с конца"},
+			PhraseOnNearestWorkday: {"в ближайший к %d-му числу рабочий день"},
+			PhraseOnLastWorkday:    {"в последний рабочий день"},
+			PhraseOfMonths:         {"%s"},
+			PhraseInMonths:         {"в течение %s"},
+			PhraseInYears:          {"в %s г."},
+			PhraseEither:           {"%s или %s"},
+			PhraseBoth:             {"%s, %s"},
+			PhraseTimeZone:         {"(%s)"},
+		},
+		names: map[string]string{
+			"понедельник": "mon", "пн": "mon",
+			"вторник": "tue", "вт": "tue",
+			"среда": "wed", "среду": "wed", "ср": "wed",
+			"четверг": "thu", "чт": "thu",
+			"пятница": "fri", "пятницу": "fri", "пт": "fri",
+			"суббота": "sat", "субботу": "sat", "сб": "sat",
+			"воскресенье": "sun", "вс": "sun",
+			"январь": "jan", "января": "jan", "янв": "jan",
+			"февраль": "feb", "февраля": "feb", "фев": "feb",
+			"март": "mar", "марта": "mar", "мар": "mar",
+			"апрель": "apr", "апреля": "apr", "апр": "apr",
+			"май": "may", "мая": "may",
+			"июнь": "jun", "июня": "jun", "июн": "jun",
+			"июль": "jul", "июля": "jul", "июл": "jul",
+			"август": "aug", "августа": "aug", "авг": "aug",
+			"сентябрь": "sep", "сентября": "sep", "сен": "sep",
+			"октябрь": "oct", "октября": "oct", "окт": "oct",
+			"ноябрь": "nov", "ноября": "nov", "ноя": "nov",
+			"декабрь": "dec", "декабря": "dec", "дек": "dec",
+		},
+	}
+
+	Spanish Locale = &tableLocale{
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		months:   [13]string{"", "enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ordinals: []string{"", "primer", "segundo", "tercer", "cuarto", "quinto", "sexto", "séptimo", "octavo", "noveno", "décimo"},
+		ordinal:  "%dº",
+		and:      "y",
+		plural:   pluralOther,
+		phrases: [phraseCount][]string{
+			PhraseAt:               {"a las %s"},
+			PhraseEverySecond:      {"cada segundo"},
+			PhraseEveryMinute:      {"cada minuto"},
+			PhraseEveryHour:        {"cada hora"},
+			PhraseEveryDay:         {"todos los días"},
+			PhraseOfEveryMinute:    {"de cada minuto"},
+			PhraseOfEveryHour:      {"de cada hora"},
+			PhraseOfEveryMonth:     {"de cada mes"},
+			PhraseEveryNSeconds:    {"cada segundo", "cada %d segundos"},
+			PhraseEveryNMinutes:    {"cada minuto", "cada %d minutos"},
+			PhraseEveryNHours:      {"cada hora", "cada %d horas"},
+			PhraseEveryNDays:       {"cada día", "cada %d días"},
+			PhraseFromSecond:       {"desde el segundo %s"},
+			PhraseFromMinute:       {"desde el minuto %s"},
+			PhraseFromHour:         {"desde la hora %s"},
+			PhraseFromDay:          {"desde el día %s"},
+			PhraseThrough:          {"%s a %s"},
+			PhraseAtSeconds:        {"en el segundo %[2]s", "en los segundos %[2]s"},
+			PhraseAtMinutes:        {"en el minuto %[2]s", "en los minutos %[2]s"},
+			PhraseOfMinutes:        {"del minuto %[2]s", "de los minutos %[2]s"},
+			PhraseAtHours:          {"en la hora %[2]s", "en las horas %[2]s"},
+			PhraseDuringHours:      {"durante la hora %[2]s", "durante las horas %[2]s"},
+			PhraseOnWeekdays:       {"el %s"},
+			PhraseOnNthWeekday:     {"%[2]s %[3]s"},
+			PhraseOnLastWeekday:    {"último %s"},
+			PhraseOnDays:           {"el día %[2]s", "los días %[2]s"},
+			PhraseOnLastDay:        {"el último día"},
+			PhraseOnLastNDays:      {"el último día", "los últimos %d días"},
+			PhraseOnNthLastDay:     {"el %[2]s día contando desde el final"},
+			PhraseOnNearestWorkday: {"el día laborable más cercano al día %d"},
+			PhraseOnLastWorkday:    {"el último día laborable"},
+			PhraseOfMonths:         {"de %s"},
+			PhraseInMonths:         {"en %s"},
+			PhraseInYears:          {"en %s"},
+			PhraseEither:           {"%s o %s"},
+			PhraseBoth:             {"%s, %s"},
+			PhraseTimeZone:         {"(%s)"},
+		},
+		// `mar` is left to March, as martes is abbreviated `ma`
+		names: map[string]string{
+			"lunes": "mon", "lu": "mon", "lun": "mon",
+			"martes": "tue", "ma": "tue",
+			"miércoles": "wed", "miercoles": "wed", "mi": "wed", "mié": "wed",
+			"jueves": "thu", "ju": "thu", "jue": "thu",
+			"viernes": "fri", "vi": "fri", "vie": "fri",
+			"sábado": "sat", "sabado": "sat", "sá": "sat", "sáb": "sat",
+			"domingo": "sun", "do": "sun", "dom": "sun",
+			"enero": "jan", "ene": "jan",
+			"febrero": "feb",
+			"marzo":   "mar",
+			"abril":   "apr", "abr": "apr",
+			"mayo":   "may",
+			"junio":  "jun",
+			"julio":  "jul",
+			"agosto": "aug", "ago": "aug",
+			"septiembre": "sep", "setiembre": "sep", "set": "sep",
+			"octubre":   "oct",
+			"noviembre": "nov",
+			"diciembre": "dec", "dic": "dec",
+		},
+	}
+)
+
+/******************************************************************************/
+
+// pluralRussian tells the form of a noun following `n`: 1 минута, 2 минуты,
+// 5 минут.
+func pluralRussian(n int) int {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
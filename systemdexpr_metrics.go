@@ -0,0 +1,160 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/******************************************************************************/
+
+// An Observer is told about the elapses of timers by the schedulers running
+// them, such as the one of the scheduler package, to be monitored. Its
+// methods must be safe for concurrent use and return quickly.
+type Observer interface {
+	// Scheduled is called when the next elapse of a timer is known, with the
+	// zero time once the timer does not elapse anymore.
+	Scheduled(timer string, next time.Time)
+	// Started is called when a timer runs for an elapse.
+	Started(timer string, elapse, start time.Time)
+	// Missed is called for an elapse which is not run, such as one skipped
+	// because the previous run has not returned, or one jumped over by the
+	// wall clock.
+	Missed(timer string, elapse time.Time)
+}
+
+/******************************************************************************/
+
+// Metrics is an Observer which serves the values it is told over HTTP in the
+// Prometheus text exposition format. The zero value is ready to use.
+type Metrics struct {
+	// Clock tells the time, RealClock when nil.
+	Clock  Clock
+	mu     sync.Mutex
+	timers map[string]*timerMetrics
+}
+
+type timerMetrics struct {
+	next    time.Time
+	lastRun time.Time
+	runs    uint64
+	missed  uint64
+}
+
+// timer returns the metrics of a timer, m.mu being held.
+func (m *Metrics) timer(name string) *timerMetrics {
+	if m.timers == nil {
+		m.timers = make(map[string]*timerMetrics)
+	}
+	t := m.timers[name]
+	if t == nil {
+		t = &timerMetrics{}
+		m.timers[name] = t
+	}
+	return t
+}
+
+func (m *Metrics) Scheduled(timer string, next time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timer(timer).next = next
+}
+
+func (m *Metrics) Started(timer string, elapse, start time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.timer(timer)
+	t.lastRun = start
+	t.runs++
+}
+
+func (m *Metrics) Missed(timer string, elapse time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timer(timer).missed++
+}
+
+/******************************************************************************/
+
+// Families of metrics, in exposition order
+var metricFamilies = []struct {
+	name, kind, help string
+	value            func(t *timerMetrics, now time.Time) (float64, bool)
+}{
+	{
+		"systemdexpr_timer_next_run_seconds", "gauge",
+		"Seconds until the next elapse of the timer.",
+		func(t *timerMetrics, now time.Time) (float64, bool) {
+			if t.next.IsZero() {
+				return 0, false
+			}
+			if d := t.next.Sub(now); d > 0 {
+				return d.Seconds(), true
+			}
+			return 0, true
+		},
+	},
+	{
+		"systemdexpr_timer_last_run_timestamp_seconds", "gauge",
+		"Unix time of the start of the last run of the timer.",
+		func(t *timerMetrics, now time.Time) (float64, bool) {
+			if t.lastRun.IsZero() {
+				return 0, false
+			}
+			return float64(t.lastRun.Unix()) + float64(t.lastRun.Nanosecond())/1e9, true
+		},
+	},
+	{
+		"systemdexpr_timer_runs_total", "counter",
+		"Runs of the timer.",
+		func(t *timerMetrics, now time.Time) (float64, bool) {
+			return float64(t.runs), true
+		},
+	},
+	{
+		"systemdexpr_timer_missed_elapses_total", "counter",
+		"Elapses of the timer which were not run.",
+		func(t *timerMetrics, now time.Time) (float64, bool) {
+			return float64(t.missed), true
+		},
+	},
+}
+
+// Escapes label values as the text exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ServeHTTP writes the metrics of all timers, labeled by timer name.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	clock := m.Clock
+	if clock == nil {
+		clock = RealClock
+	}
+	now := clock.Now()
+
+	var buf bytes.Buffer
+	m.mu.Lock()
+	names := make([]string, 0, len(m.timers))
+	for name := range m.timers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, family := range metricFamilies {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		for _, name := range names {
+			if value, ok := family.value(m.timers[name], now); ok {
+				fmt.Fprintf(&buf, "%s{timer=\"%s\"} %s\n", family.name, labelEscaper.Replace(name), strconv.FormatFloat(value, 'f', -1, 64))
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
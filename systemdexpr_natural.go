@@ -0,0 +1,604 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+var (
+	naturalTimeFinder    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm)?$`)
+	naturalOrdinalFinder = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)$`)
+
+	naturalOrdinals = map[string]int{
+		"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "last": -1,
+	}
+
+	// Words understood by ParseNatural, besides weekday and month names
+	naturalVocabulary = []string{
+		"every", "each", "at", "in", "on", "the", "of", "and", "through", "to",
+		"second", "seconds", "minute", "minutes", "hour", "hours",
+		"day", "week", "weekday", "weekdays", "weekend", "weekends", "workday", "workdays",
+		"month", "quarter", "year", "first", "third", "fourth", "fifth", "last",
+		"noon", "midnight", "am", "pm",
+		"hourly", "daily", "weekly", "monthly", "quarterly", "yearly", "annually",
+	}
+)
+
+/******************************************************************************/
+
+// A NaturalError tells why ParseNatural could not read a phrase. Suggestion
+// is a likely intended word when the offending word looks misspelled, or an
+// example of what was expected otherwise.
+type NaturalError struct {
+	Phrase     string
+	Word       string // empty at the end of the phrase
+	Reason     string
+	Suggestion string
+	// Err is the error of Parse when the phrase compiles into an invalid
+	// systemd expression, such as for "every 61 minutes".
+	Err error
+}
+
+func (e *NaturalError) Error() string {
+	msg := "natural: " + e.Reason
+	if e.Word != "" {
+		msg += fmt.Sprintf(" '%s'", e.Word)
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean '%s'?", e.Suggestion)
+	}
+	return msg
+}
+
+func (e *NaturalError) Unwrap() error {
+	return e.Err
+}
+
+/******************************************************************************/
+
+// ParseNatural returns a new Expression pointer built from an English phrase
+// such as "every 15 minutes", "weekdays at 9:30", "first Monday of the month
+// at noon" or "last day of every quarter". The phrase is compiled into a
+// systemd expression which is read as with Options.MatchBothDays. A
+// *NaturalError is returned if the phrase cannot be understood.
+func ParseNatural(phrase string) (*Expression, error) {
+	p := naturalParser{phrase: phrase, words: naturalWords(phrase)}
+	systemdLine, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	expr, err := ParseWithOptions(systemdLine, Options{MatchBothDays: true})
+	if err != nil {
+		return nil, &NaturalError{Phrase: phrase, Reason: err.Error(), Err: err}
+	}
+	expr.expression = phrase
+	return expr, nil
+}
+
+func naturalWords(phrase string) []string {
+	return strings.FieldsFunc(strings.ToLower(phrase), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '.'
+	})
+}
+
+/******************************************************************************/
+
+type naturalParser struct {
+	phrase string
+	words  []string
+	i      int
+
+	weekdays    []string
+	months      string // systemd month field
+	days        string // systemd day field with its `-` or `~` separator
+	defaultDays string // used when no day is given, such as for "monthly"
+	defaultDow  string // used when no day is given, such as for "weekly"
+	repeatUnit  string // "second", "minute" or "hour"
+	repeatStep  int
+	times       [][3]int
+}
+
+func (p *naturalParser) peek() string {
+	if p.i < len(p.words) {
+		return p.words[p.i]
+	}
+	return ""
+}
+
+func (p *naturalParser) next() string {
+	w := p.peek()
+	p.i++
+	return w
+}
+
+// fail reports the word just read, or the end of the phrase.
+func (p *naturalParser) fail(reason, suggestion string) error {
+	word := ""
+	if p.i > 0 && p.i <= len(p.words) {
+		word = p.words[p.i-1]
+	} else if p.i > len(p.words) {
+		reason = "unexpected end of phrase"
+	}
+	return &NaturalError{Phrase: p.phrase, Word: word, Reason: reason, Suggestion: suggestion}
+}
+
+func (p *naturalParser) parse() (string, error) {
+	if len(p.words) == 0 {
+		return "", &NaturalError{Phrase: p.phrase, Reason: "empty phrase", Suggestion: "every day at 9:00"}
+	}
+	fillers := 0
+	for p.i < len(p.words) {
+		var err error
+		w := p.next()
+		_, isWeekday := naturalWeekday(w)
+		_, isOrdinal := naturalOrdinal(w)
+		switch {
+		case w == "every" || w == "each":
+			err = p.every()
+		case w == "at":
+			err = p.at()
+		case w == "in":
+			err = p.in()
+		case w == "on" || w == "the" || w == "and":
+			fillers++
+		case w == "hourly":
+			err = p.setRepeat("hour", 1)
+		case w == "daily":
+		case w == "weekly":
+			p.defaultDow = "Mon"
+		case w == "monthly":
+			p.defaultDays = "-01"
+		case w == "quarterly":
+			err = p.setMonths("01,04,07,10")
+			p.defaultDays = "-01"
+		case w == "yearly" || w == "annually":
+			err = p.setMonths("01")
+			p.defaultDays = "-01"
+		case w == "weekdays" || w == "workdays":
+			p.weekdays = append(p.weekdays, "Mon..Fri")
+		case w == "weekends":
+			p.weekdays = append(p.weekdays, "Sat,Sun")
+		case isWeekday:
+			p.i--
+			err = p.weekdayList()
+		case isOrdinal:
+			p.i--
+			err = p.ordinal()
+		case w == "noon" || w == "midnight" || naturalTimeFinder.MatchString(w):
+			p.i--
+			err = p.at()
+		default:
+			err = p.unknown(w)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if fillers == len(p.words) {
+		return "", &NaturalError{Phrase: p.phrase, Reason: "nothing to schedule", Suggestion: "every day at 9:00"}
+	}
+	return p.compile()
+}
+
+func (p *naturalParser) unknown(w string) error {
+	if suggestion := naturalSuggestion(w); suggestion != "" {
+		return p.fail("unknown word", suggestion)
+	}
+	return p.fail("unknown word", "")
+}
+
+/******************************************************************************/
+
+// every reads what follows `every`.
+func (p *naturalParser) every() error {
+	w := p.next()
+	if n, err := strconv.Atoi(w); err == nil {
+		unit := strings.TrimSuffix(p.next(), "s")
+		switch {
+		case unit == "hour" && n == 24:
+			// daily
+			return nil
+		case unit == "minute" && n == 60:
+			return p.setRepeat("hour", 1)
+		case unit == "second" && n == 60:
+			return p.setRepeat("minute", 1)
+		case unit == "second" || unit == "minute" || unit == "hour":
+			return p.setRepeat(unit, n)
+		}
+		return p.fail("unexpected word", fmt.Sprintf("every %d minutes", n))
+	}
+	if _, isOrdinal := naturalOrdinal(w); isOrdinal {
+		// "every second Tuesday" means every other week, which systemd
+		// cannot express, rather than every second
+		next := p.peek()
+		if _, isWeekday := naturalWeekday(next); isWeekday || next == "day" {
+			return p.fail("ambiguous repetition", fmt.Sprintf("%s %s of every month", w, next))
+		}
+	}
+	switch w {
+	case "second", "minute", "hour":
+		return p.setRepeat(w, 1)
+	case "day":
+	case "weekday", "workday":
+		p.weekdays = append(p.weekdays, "Mon..Fri")
+	case "weekend":
+		p.weekdays = append(p.weekdays, "Sat,Sun")
+	case "week":
+		p.defaultDow = "Mon"
+	case "month":
+		p.defaultDays = "-01"
+	case "quarter":
+		p.defaultDays = "-01"
+		return p.setMonths("01,04,07,10")
+	case "year":
+		p.defaultDays = "-01"
+		return p.setMonths("01")
+	default:
+		if _, isWeekday := naturalWeekday(w); isWeekday {
+			p.i--
+			return p.weekdayList()
+		}
+		if w == "" {
+			return p.fail("", "every 15 minutes")
+		}
+		return p.unknown(w)
+	}
+	return nil
+}
+
+// weekdayList reads weekday names joined by `and`, or a `through` range.
+func (p *naturalParser) weekdayList() error {
+	for {
+		first, _ := naturalWeekday(p.next())
+		switch p.peek() {
+		case "through", "to", "-":
+			p.next()
+			last, isWeekday := naturalWeekday(p.next())
+			if !isWeekday {
+				return p.fail("expected weekday instead of", "Monday through Friday")
+			}
+			p.weekdays = append(p.weekdays, first+".."+last)
+		default:
+			p.weekdays = append(p.weekdays, first)
+		}
+		if p.peek() != "and" || p.i+1 >= len(p.words) {
+			return nil
+		}
+		if _, isWeekday := naturalWeekday(p.words[p.i+1]); !isWeekday {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// ordinal reads "first Monday of the month" or "last day of every quarter".
+func (p *naturalParser) ordinal() error {
+	n, _ := naturalOrdinal(p.next())
+	numeric := naturalOrdinalFinder.MatchString(p.words[p.i-1])
+	dow, isWeekday := naturalWeekday(p.peek())
+	switch {
+	case isWeekday:
+		if n > 5 {
+			return p.fail("no such weekday", "first Monday")
+		}
+		p.next()
+		p.weekdays = append(p.weekdays, dow)
+		if n < 0 {
+			return p.setDays("~07..01", p.period(n))
+		}
+		last := 7 * n
+		if last > domDescriptor.max {
+			last = domDescriptor.max
+		}
+		return p.setDays(fmt.Sprintf("-%02d..%02d", 7*n-6, last), p.period(n))
+	case p.peek() == "day":
+		p.next()
+	case !numeric:
+		p.next()
+		return p.fail("expected day or weekday instead of", "first day of the month")
+	}
+	if n < 0 {
+		return p.setDays("~01", p.period(n))
+	}
+	if n > 31 {
+		return p.fail("no such day", "")
+	}
+	return p.setDays(fmt.Sprintf("-%02d", n), p.period(n))
+}
+
+// period reads the optional "of the month" following an ordinal day, and
+// returns the months it restricts, "" for every month.
+func (p *naturalParser) period(n int) string {
+	if p.peek() != "of" {
+		return ""
+	}
+	p.next()
+	switch p.peek() {
+	case "the", "every", "each":
+		p.next()
+	}
+	switch p.peek() {
+	case "month":
+		p.next()
+	case "quarter":
+		p.next()
+		if n < 0 {
+			return "03,06,09,12"
+		}
+		return "01,04,07,10"
+	case "year":
+		p.next()
+		if n < 0 {
+			return "12"
+		}
+		return "01"
+	default:
+		if month, isMonth := naturalMonth(p.peek()); isMonth {
+			p.next()
+			return month
+		}
+	}
+	return ""
+}
+
+// at reads times joined by `and`.
+func (p *naturalParser) at() error {
+	for {
+		t, err := p.time()
+		if err != nil {
+			return err
+		}
+		p.times = append(p.times, t)
+		if p.peek() != "and" || p.i+1 >= len(p.words) {
+			return nil
+		}
+		if w := p.words[p.i+1]; w != "noon" && w != "midnight" && !naturalTimeFinder.MatchString(w) {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *naturalParser) time() ([3]int, error) {
+	w := p.next()
+	switch w {
+	case "noon":
+		return [3]int{12, 0, 0}, nil
+	case "midnight":
+		return [3]int{0, 0, 0}, nil
+	}
+	match := naturalTimeFinder.FindStringSubmatch(w)
+	if match == nil {
+		return [3]int{}, p.fail("expected time instead of", "at 9:30")
+	}
+	suffix := match[4]
+	if suffix == "" && (p.peek() == "am" || p.peek() == "pm") {
+		suffix = p.next()
+	}
+	var t [3]int
+	for i := range t {
+		t[i], _ = strconv.Atoi(match[i+1])
+	}
+	switch {
+	case suffix != "" && (t[0] < 1 || t[0] > 12):
+		return t, p.fail("invalid time", fmt.Sprintf("%d:%02d", t[0], t[1]))
+	case suffix == "am" && t[0] == 12:
+		t[0] = 0
+	case suffix == "pm" && t[0] != 12:
+		t[0] += 12
+	}
+	if t[0] > 23 || t[1] > 59 || t[2] > 59 {
+		return t, p.fail("invalid time", "")
+	}
+	return t, nil
+}
+
+// in reads month names joined by `and`, or a `through` range.
+func (p *naturalParser) in() error {
+	var months []string
+	for {
+		w := p.next()
+		first, isMonth := naturalMonth(w)
+		if !isMonth {
+			if suggestion := naturalSuggestion(w); suggestion != "" {
+				return p.fail("unknown word", suggestion)
+			}
+			return p.fail("expected month instead of", "in January")
+		}
+		switch p.peek() {
+		case "through", "to", "-":
+			p.next()
+			last, isMonth := naturalMonth(p.next())
+			if !isMonth {
+				return p.fail("expected month instead of", "in June through August")
+			}
+			months = append(months, first+".."+last)
+		default:
+			months = append(months, first)
+		}
+		if p.peek() != "and" {
+			break
+		}
+		p.next()
+	}
+	return p.setMonths(strings.Join(months, ","))
+}
+
+/******************************************************************************/
+
+func (p *naturalParser) setRepeat(unit string, n int) error {
+	if p.repeatUnit != "" {
+		return p.fail("conflicting repetition", "")
+	}
+	if n < 1 {
+		return p.fail("invalid repetition", fmt.Sprintf("every 15 %ss", unit))
+	}
+	p.repeatUnit, p.repeatStep = unit, n
+	return nil
+}
+
+func (p *naturalParser) setDays(days, months string) error {
+	if p.days != "" {
+		return p.fail("conflicting days", "")
+	}
+	p.days = days
+	if months != "" {
+		return p.setMonths(months)
+	}
+	return nil
+}
+
+func (p *naturalParser) setMonths(months string) error {
+	if p.months != "" && p.months != months {
+		return p.fail("conflicting months", "")
+	}
+	p.months = months
+	return nil
+}
+
+// compile assembles the systemd expression.
+func (p *naturalParser) compile() (string, error) {
+	var clock string
+	switch {
+	case p.repeatUnit != "" && len(p.times) > 0:
+		return "", &NaturalError{Phrase: p.phrase, Reason: "repetition cannot be combined with times of day", Suggestion: "every 15 minutes"}
+	case p.repeatUnit == "second":
+		clock = "*:*:0/" + strconv.Itoa(p.repeatStep)
+	case p.repeatUnit == "minute":
+		clock = "*:0/" + strconv.Itoa(p.repeatStep) + ":00"
+	case p.repeatUnit == "hour":
+		clock = "0/" + strconv.Itoa(p.repeatStep) + ":00:00"
+	case len(p.times) > 0:
+		// times of day are the product of their hours, minutes and seconds
+		var sets [3]map[int]bool
+		for i := range sets {
+			sets[i] = make(map[int]bool)
+			for _, t := range p.times {
+				sets[i][t[i]] = true
+			}
+		}
+		if len(sets[0])*len(sets[1])*len(sets[2]) != len(p.times) {
+			return "", &NaturalError{Phrase: p.phrase, Reason: "times of day cannot be combined", Suggestion: "at 9:00 and 17:00"}
+		}
+		parts := make([]string, 3)
+		for i, set := range sets {
+			values := make([]string, 0, len(set))
+			for _, v := range mapToList(set) {
+				values = append(values, fmt.Sprintf("%02d", v))
+			}
+			parts[i] = strings.Join(values, ",")
+		}
+		clock = strings.Join(parts, ":")
+	default:
+		clock = "00:00:00"
+	}
+
+	days := p.days
+	if days == "" {
+		days = p.defaultDays
+		if len(p.weekdays) > 0 || p.defaultDow != "" {
+			days = ""
+		}
+	}
+	if days == "" {
+		days = "-*"
+	}
+	if len(p.weekdays) == 0 && p.defaultDow != "" {
+		p.weekdays = []string{p.defaultDow}
+	}
+	months := p.months
+	if months == "" {
+		months = "*"
+	}
+	return strings.TrimSpace(strings.Join(p.weekdays, ",") + " *-" + months + days + " " + clock), nil
+}
+
+/******************************************************************************/
+
+// naturalWeekday returns the systemd name of an English weekday, which may
+// be plural or abbreviated.
+func naturalWeekday(w string) (string, bool) {
+	for d, name := range systemdWeekdayNames {
+		full := strings.ToLower(time.Weekday(d).String())
+		if w == full || w == full+"s" || w == strings.ToLower(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// naturalMonth returns the systemd month of an English month name.
+func naturalMonth(w string) (string, bool) {
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if w == full || w == full[:3] {
+			return fmt.Sprintf("%02d", int(m)), true
+		}
+	}
+	return "", false
+}
+
+// naturalOrdinal returns -1 for `last`.
+func naturalOrdinal(w string) (int, bool) {
+	if n, found := naturalOrdinals[w]; found {
+		return n, true
+	}
+	if match := naturalOrdinalFinder.FindStringSubmatch(w); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return n, n > 0
+	}
+	return 0, false
+}
+
+// naturalSuggestion returns the known word closest to a misspelled one.
+func naturalSuggestion(w string) string {
+	candidates := append([]string(nil), naturalVocabulary...)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		candidates = append(candidates, strings.ToLower(d.String()))
+	}
+	for m := time.January; m <= time.December; m++ {
+		candidates = append(candidates, strings.ToLower(m.String()))
+	}
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := levenshtein(w, candidate); d < bestDistance && d < len([]rune(w)) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		previous := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current := row[j]
+			row[j] = previous + cost
+			if current+1 < row[j] {
+				row[j] = current + 1
+			}
+			if row[j-1]+1 < row[j] {
+				row[j] = row[j-1] + 1
+			}
+			previous = current
+		}
+	}
+	return row[len(rb)]
+}
@@ -31,12 +31,20 @@ func (expr *Expression) calculateActualDaysOfMonth(year, month int) []int {
 	//  "fields - day of month, and day of week. If both fields are
 	//  "restricted (ie, aren't *), the command will be run when
 	//  "either field matches the current time"
+	// systemd on the other hand requires both of them to match.
 
 	// If both fields are not restricted, all days of the month are a hit
 	if expr.daysOfMonthRestricted == false && expr.daysOfWeekRestricted == false {
 		return genericDefaultList[1 : lastDayOfMonth.Day()+1]
 	}
 
+	// Days of week go to their own map when both fields must match
+	daysOfWeekMap := actualDaysOfMonthMap
+	intersect := expr.bothDaysRequired && expr.daysOfMonthRestricted && expr.daysOfWeekRestricted
+	if intersect {
+		daysOfWeekMap = make(map[int]bool)
+	}
+
 	// day-of-month != `*`
 	if expr.daysOfMonthRestricted {
 		// Last day of month
@@ -54,6 +62,12 @@ func (expr *Expression) calculateActualDaysOfMonth(year, month int) []int {
 				actualDaysOfMonthMap[v] = true
 			}
 		}
+		// Days counted from the end of month
+		for v := range expr.daysFromEndOfMonth {
+			if v <= lastDayOfMonth.Day() {
+				actualDaysOfMonthMap[lastDayOfMonth.Day()-v+1] = true
+			}
+		}
 		// Work days of month
 		// As per Wikipedia: month boundaries are not crossed.
 		for v := range expr.workdaysOfMonth {
@@ -73,12 +87,12 @@ func (expr *Expression) calculateActualDaysOfMonth(year, month int) []int {
 		//  target : 1 + (7 * week_of_month) + (offset + day_of_week) % 7
 		for v := range expr.daysOfWeek {
 			w := dowNormalizedOffsets[(offset+v)%7]
-			actualDaysOfMonthMap[w[0]] = true
-			actualDaysOfMonthMap[w[1]] = true
-			actualDaysOfMonthMap[w[2]] = true
-			actualDaysOfMonthMap[w[3]] = true
+			daysOfWeekMap[w[0]] = true
+			daysOfWeekMap[w[1]] = true
+			daysOfWeekMap[w[2]] = true
+			daysOfWeekMap[w[3]] = true
 			if len(w) > 4 && w[4] <= lastDayOfMonth.Day() {
-				actualDaysOfMonthMap[w[4]] = true
+				daysOfWeekMap[w[4]] = true
 			}
 		}
 		// days of week of specific week in the month
@@ -87,7 +101,7 @@ func (expr *Expression) calculateActualDaysOfMonth(year, month int) []int {
 		for v := range expr.specificWeekDaysOfWeek {
 			v = 1 + 7*(v/7) + (offset+v)%7
 			if v <= lastDayOfMonth.Day() {
-				actualDaysOfMonthMap[v] = true
+				daysOfWeekMap[v] = true
 			}
 		}
 		// Last days of week of the month
@@ -96,7 +110,15 @@ func (expr *Expression) calculateActualDaysOfMonth(year, month int) []int {
 		for v := range expr.lastWeekDaysOfWeek {
 			v = lastWeekOrigin.Day() + (offset+v)%7
 			if v <= lastDayOfMonth.Day() {
-				actualDaysOfMonthMap[v] = true
+				daysOfWeekMap[v] = true
+			}
+		}
+	}
+
+	if intersect {
+		for v := range actualDaysOfMonthMap {
+			if !daysOfWeekMap[v] {
+				delete(actualDaysOfMonthMap, v)
 			}
 		}
 	}
@@ -192,7 +192,7 @@ var (
 
 /******************************************************************************/
 
-var systemdNormalizer = strings.NewReplacer(
+var systemdAliases = []string{
 	"minutely", "*-*-* *:*:00",
 	"hourly", "*-*-* *:00:00",
 	"daily", "*-*-* 00:00:00",
@@ -202,7 +202,9 @@ var systemdNormalizer = strings.NewReplacer(
 	"annually", "*-01-01 00:00:00",
 	"quarterly", "*-01,04,07,10-01 00:00:00",
 	"semiannually", "*-01,07-01 00:00:00",
-)
+}
+
+var systemdNormalizer = strings.NewReplacer(systemdAliases...)
 
 type FieldType uint8
 
@@ -210,6 +212,7 @@ const (
 	WeekDayField FieldType = 0
 	DayField     FieldType = 1
 	TimeField    FieldType = 2
+	MonthField   FieldType = 3
 )
 
 var systemdFieldsSig = map[FieldType]string{
@@ -220,15 +223,124 @@ var systemdFieldsSig = map[FieldType]string{
 
 /******************************************************************************/
 
-func (expr *Expression) normalyzeSystemd() error {
+// A ParseError is returned by Parse for a malformed expression. It tells
+// which token of the expression is at fault.
+type ParseError struct {
+	// Expression is the expression with aliases expanded, in which Offset
+	// is a byte offset.
+	Expression string
+	Token      string
+	Offset     int
+	Err        error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Token quoted by an error message
+var errorTokenFinder = regexp.MustCompile(`'~?([^']+)'`)
+
+// newParseError blames the `length` bytes of `expression` found at `offset`,
+// or the token quoted by the error message when it is found among them.
+func newParseError(err error, expression string, offset, length int) *ParseError {
+	if m := errorTokenFinder.FindStringSubmatch(err.Error()); m != nil {
+		if i := strings.Index(strings.ToLower(expression[offset:offset+length]), m[1]); i >= 0 {
+			offset += i
+			length = len(m[1])
+		}
+	}
+	return &ParseError{
+		Expression: expression,
+		Token:      expression[offset : offset+length],
+		Offset:     offset,
+		Err:        err,
+	}
+}
+
+/******************************************************************************/
+
+func (expr *Expression) normalyzeSystemd(options Options) (string, error) {
+	if options.NoAliases {
+		for _, field := range strings.Fields(strings.ToLower(expr.expression)) {
+			for i := 0; i < len(systemdAliases); i += 2 {
+				if field == systemdAliases[i] {
+					return "", fmt.Errorf("alias '%s' is not allowed", field)
+				}
+			}
+		}
+	}
 	// Maybe one of the built-in aliases is being used
-	expr.expression = systemdNormalizer.Replace(expr.expression)
-	expr.expression = strings.ToLower(expr.expression)
+	original := systemdNormalizer.Replace(expr.expression)
+	if parser, ok := options.Locale.(NameParser); ok {
+		original = translateNames(original, parser)
+	}
+	expr.expression = strings.ToLower(original)
+	return original, nil
+}
+
+/******************************************************************************/
+
+// Directives which only make sense to cron, rejected in strict mode
+var systemdStrictForbidden = map[FieldType]*regexp.Regexp{
+	WeekDayField: regexp.MustCompile(`[-#]|l(,|$)`),
+	DayField:     regexp.MustCompile(`[lw#?]`),
+	MonthField:   regexp.MustCompile(`[^\d,*./]`),
+}
+
+// English month names, which a Locale translates the names of its language to
+var monthNameFinder = regexp.MustCompile(`jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec`)
+
+func checkStrictField(s string, sigType FieldType, options Options) error {
+	checked := s
+	if _, ok := options.Locale.(NameParser); ok && sigType == MonthField {
+		checked = monthNameFinder.ReplaceAllString(s, "")
+	}
+	if systemdStrictForbidden[sigType].MatchString(checked) {
+		desc := dowDescriptor
+		switch sigType {
+		case DayField:
+			desc = domDescriptor
+		case MonthField:
+			desc = monthDescriptor
+		}
+		return fmt.Errorf("syntax error in %s field: '%s' is not systemd syntax", desc.name, s)
+	}
 	return nil
 }
 
 /******************************************************************************/
 
+func expandTwoDigitYears(s string, mode TwoDigitYearMode) string {
+	entries := strings.Split(s, ",")
+	for i, entry := range entries {
+		// `/2` is a step, never a year
+		value, step, hasStep := strings.Cut(entry, "/")
+		bounds := strings.Split(value, "..")
+		for j, bound := range bounds {
+			if len(bound) != 2 || bound[0] < '0' || bound[0] > '9' || bound[1] < '0' || bound[1] > '9' {
+				continue
+			}
+			if mode == TwoDigitYear1970 && bound >= "70" {
+				bounds[j] = "19" + bound
+			} else {
+				bounds[j] = "20" + bound
+			}
+		}
+		entries[i] = strings.Join(bounds, "..")
+		if hasStep {
+			entries[i] += "/" + step
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+/******************************************************************************/
+
 func (expr *Expression) validateField(field int, sigType FieldType, indices [][]int) bool {
 	fieldCount := len(indices)
 	if field >= fieldCount {
@@ -245,7 +357,7 @@ func (expr *Expression) validateField(field int, sigType FieldType, indices [][]
 
 func (expr *Expression) secondFieldHandler(s string) error {
 	var err error
-	expr.secondList, err = genericFieldHandler(s, secondDescriptor)
+	expr.secondList, err = expr.genericFieldHandler(s, secondDescriptor)
 	return err
 }
 
@@ -253,7 +365,7 @@ func (expr *Expression) secondFieldHandler(s string) error {
 
 func (expr *Expression) minuteFieldHandler(s string) error {
 	var err error
-	expr.minuteList, err = genericFieldHandler(s, minuteDescriptor)
+	expr.minuteList, err = expr.genericFieldHandler(s, minuteDescriptor)
 	return err
 }
 
@@ -261,7 +373,7 @@ func (expr *Expression) minuteFieldHandler(s string) error {
 
 func (expr *Expression) hourFieldHandler(s string) error {
 	var err error
-	expr.hourList, err = genericFieldHandler(s, hourDescriptor)
+	expr.hourList, err = expr.genericFieldHandler(s, hourDescriptor)
 	return err
 }
 
@@ -269,7 +381,7 @@ func (expr *Expression) hourFieldHandler(s string) error {
 
 func (expr *Expression) monthFieldHandler(s string) error {
 	var err error
-	expr.monthList, err = genericFieldHandler(s, monthDescriptor)
+	expr.monthList, err = expr.genericFieldHandler(s, monthDescriptor)
 	return err
 }
 
@@ -277,7 +389,7 @@ func (expr *Expression) monthFieldHandler(s string) error {
 
 func (expr *Expression) yearFieldHandler(s string) error {
 	var err error
-	expr.yearList, err = genericFieldHandler(s, yearDescriptor)
+	expr.yearList, err = expr.genericFieldHandler(s, yearDescriptor)
 	return err
 }
 
@@ -295,15 +407,19 @@ type cronDirective struct {
 	first int
 	last  int
 	step  int
+	open  bool // `5/2`, repeated up to the end of the field
 	sbeg  int
 	send  int
 }
 
-func genericFieldHandler(s string, desc fieldDescriptor) ([]int, error) {
+// genericFieldHandler returns the values of a field, and records its
+// directives for String.
+func (expr *Expression) genericFieldHandler(s string, desc fieldDescriptor) ([]int, error) {
 	directives, err := genericFieldParse(s, desc)
 	if err != nil {
 		return nil, err
 	}
+	expr.setDirectives(desc.name, directives)
 	values := make(map[int]bool)
 	for _, directive := range directives {
 		switch directive.kind {
@@ -352,11 +468,16 @@ func (expr *Expression) dowFieldHandler(s string) error {
 		case one:
 			populateOne(expr.daysOfWeek, directive.first)
 		case span:
-			// To properly handle spans that end in 7 (Sunday)
+			// To properly handle spans that end in 7 (Sunday), which is
+			// stored as 0
 			if directive.last == 0 {
-				directive.last = 6
+				directive.last = 7
 			}
 			populateMany(expr.daysOfWeek, directive.first, directive.last, directive.step)
+			if expr.daysOfWeek[7] {
+				delete(expr.daysOfWeek, 7)
+				expr.daysOfWeek[0] = true
+			}
 		case all:
 			populateMany(expr.daysOfWeek, directive.first, directive.last, directive.step)
 			expr.daysOfWeekRestricted = false
@@ -371,11 +492,14 @@ func (expr *Expression) domFieldHandler(s string) error {
 	expr.lastWorkdayOfMonth = false
 	expr.daysOfMonth = make(map[int]bool)     // days of month map
 	expr.workdaysOfMonth = make(map[int]bool) // work days of month map
+	expr.daysFromEndOfMonth = nil
 
 	directives, err := genericFieldParse(s, domDescriptor)
 	if err != nil {
 		return err
 	}
+	expr.setDirectives(domDescriptor.name, directives)
+	expr.setDirectives(fromEndDirectivesKey, nil)
 
 	for _, directive := range directives {
 		switch directive.kind {
@@ -411,6 +535,52 @@ func (expr *Expression) domFieldHandler(s string) error {
 	return nil
 }
 
+func (expr *Expression) domFromEndFieldHandler(s string) error {
+	err := expr.domFieldHandler("*")
+	if err != nil {
+		return err
+	}
+	expr.daysOfMonth = make(map[int]bool)
+	expr.daysFromEndOfMonth = make(map[int]bool) // 1 is the last day of month
+
+	directives, err := genericFieldParse(s, domDescriptor)
+	if err != nil {
+		return err
+	}
+	expr.setDirectives(fromEndDirectivesKey, directives)
+
+	for _, directive := range directives {
+		sdirective := s[directive.sbeg:directive.send]
+		switch directive.kind {
+		case none:
+			return fmt.Errorf("syntax error in day-of-month field: '~%s'", sdirective)
+		case one:
+			populateOne(expr.daysFromEndOfMonth, directive.first)
+		case span:
+			if strings.Contains(sdirective, "..") {
+				// `~07..01`, repeated from its start as written: `~05..01/3` is
+				// the 5th and 2nd days from the end
+				if directive.first > directive.last {
+					for v := directive.first; v >= directive.last; v -= directive.step {
+						populateOne(expr.daysFromEndOfMonth, v)
+					}
+				} else {
+					populateMany(expr.daysFromEndOfMonth, directive.first, directive.last, directive.step)
+				}
+			} else {
+				// `~07/2`, repeated towards the end of the month
+				for v := directive.first; v >= domDescriptor.min; v -= directive.step {
+					populateOne(expr.daysFromEndOfMonth, v)
+				}
+			}
+		case all:
+			return expr.domFieldHandler("*")
+		}
+	}
+	expr.daysOfMonthRestricted = true
+	return nil
+}
+
 /******************************************************************************/
 
 func populateOne(values map[int]bool, v int) {
@@ -494,6 +664,7 @@ func genericFieldParse(s string, desc fieldDescriptor) ([]*cronDirective, error)
 			directive.kind = span
 			directive.first = desc.min
 			directive.last = desc.max
+			directive.open = true
 			directive.step = atoi(snormal[pairs[2]:pairs[3]])
 			if directive.step < 1 || directive.step > desc.max {
 				return nil, fmt.Errorf("invalid interval %s", snormal)
@@ -507,6 +678,7 @@ func genericFieldParse(s string, desc fieldDescriptor) ([]*cronDirective, error)
 			directive.kind = span
 			directive.first = desc.atoi(snormal[pairs[2]:pairs[3]])
 			directive.last = desc.max
+			directive.open = true
 			directive.step = atoi(snormal[pairs[4]:pairs[5]])
 			if directive.step < 1 || directive.step > desc.max {
 				return nil, fmt.Errorf("invalid interval %s", snormal)
@@ -0,0 +1,321 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+// RRULE frequencies, from the finest to the coarsest
+const (
+	freqSecondly = iota
+	freqMinutely
+	freqHourly
+	freqDaily
+	freqWeekly
+	freqMonthly
+	freqYearly
+)
+
+var rruleFrequencies = []string{"SECONDLY", "MINUTELY", "HOURLY", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+/******************************************************************************/
+
+// ToRRule converts an expression into an iCalendar recurrence rule as defined
+// by RFC 5545, such as `FREQ=WEEKLY;BYDAY=MO,TU;BYHOUR=9;BYMINUTE=0;BYSECOND=0`.
+//
+// A recurrence rule is evaluated from its DTSTART, so an expression carrying a
+// time zone or starting after 1970 cannot be represented, nor can the nearest
+// workday directives or a cron day of month or day of week: an explanatory
+// error is returned for them.
+func ToRRule(expr *Expression) (string, error) {
+	if expr.timeZone != nil {
+		return "", fmt.Errorf("rrule: time zone '%s' must be given by DTSTART", expr.timeZone)
+	}
+	parts, lastYear, err := expr.rruleParts()
+	if err != nil {
+		return "", err
+	}
+	if lastYear != 0 {
+		parts = append(parts, fmt.Sprintf("UNTIL=%04d1231T235959", lastYear))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// rruleParts returns the parts of the recurrence rule of the expression but
+// UNTIL, which is left to the caller along with the last year of the
+// expression, 0 when there is none.
+func (expr *Expression) rruleParts() ([]string, int, error) {
+	if len(expr.workdaysOfMonth) > 0 || expr.lastWorkdayOfMonth {
+		return nil, 0, fmt.Errorf("rrule: nearest workday cannot be represented")
+	}
+	if expr.daysOfMonthRestricted && expr.daysOfWeekRestricted && !expr.bothDaysRequired {
+		return nil, 0, fmt.Errorf("rrule: day of month or day of week cannot be represented")
+	}
+
+	// years
+	lastYear := 0
+	if !isDefaultList(expr.yearList, yearDescriptor) {
+		segments := segmentList(expr.yearList, yearDescriptor.max)
+		if len(expr.yearList) == 0 || len(segments) != 1 || segments[0].step != 1 {
+			return nil, 0, fmt.Errorf("rrule: years must be a single range")
+		}
+		if segments[0].first != yearDescriptor.min {
+			return nil, 0, fmt.Errorf("rrule: first year %d must be given by DTSTART", segments[0].first)
+		}
+		lastYear = segments[0].last
+	}
+
+	// days
+	var byMonthDay, byDay []string
+	ordinals := false
+	if expr.daysOfMonthRestricted {
+		for _, v := range mapToList(expr.daysOfMonth) {
+			byMonthDay = append(byMonthDay, strconv.Itoa(v))
+		}
+		fromEnd := mapToList(expr.daysFromEndOfMonth)
+		if expr.lastDayOfMonth && !sortContains(fromEnd, 1) {
+			fromEnd = append([]int{1}, fromEnd...)
+		}
+		for _, v := range fromEnd {
+			byMonthDay = append(byMonthDay, strconv.Itoa(-v))
+		}
+	}
+	if expr.daysOfWeekRestricted {
+		for _, v := range mapToList(expr.daysOfWeek) {
+			byDay = append(byDay, rruleWeekdays[v])
+		}
+		for _, v := range mapToList(expr.specificWeekDaysOfWeek) {
+			byDay = append(byDay, strconv.Itoa(v/7+1)+rruleWeekdays[v%7])
+			ordinals = true
+		}
+		for _, v := range mapToList(expr.lastWeekDaysOfWeek) {
+			byDay = append(byDay, "-1"+rruleWeekdays[v])
+			ordinals = true
+		}
+	}
+
+	// The frequency is that of the finest unrestricted component, so that
+	// every finer component is listed
+	freq := freqYearly
+	monthsRestricted := !isDefaultList(expr.monthList, monthDescriptor)
+	switch {
+	case ordinals && monthsRestricted:
+		freq = freqYearly
+	case ordinals:
+		freq = freqMonthly
+	case isDefaultList(expr.secondList, secondDescriptor):
+		freq = freqSecondly
+	case isDefaultList(expr.minuteList, minuteDescriptor):
+		freq = freqMinutely
+	case isDefaultList(expr.hourList, hourDescriptor):
+		freq = freqHourly
+	case !expr.daysOfMonthRestricted && !expr.daysOfWeekRestricted:
+		freq = freqDaily
+	case !expr.daysOfMonthRestricted:
+		freq = freqWeekly
+	case !monthsRestricted:
+		freq = freqMonthly
+	}
+
+	parts := []string{"FREQ=" + rruleFrequencies[freq]}
+	if monthsRestricted {
+		parts = append(parts, "BYMONTH="+joinInts(expr.monthList))
+	}
+	if len(byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+strings.Join(byMonthDay, ","))
+	}
+	if len(byDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(byDay, ","))
+	}
+	if freq > freqHourly || !isDefaultList(expr.hourList, hourDescriptor) {
+		parts = append(parts, "BYHOUR="+joinInts(expr.hourList))
+	}
+	if freq > freqMinutely || !isDefaultList(expr.minuteList, minuteDescriptor) {
+		parts = append(parts, "BYMINUTE="+joinInts(expr.minuteList))
+	}
+	if freq > freqSecondly || !isDefaultList(expr.secondList, secondDescriptor) {
+		parts = append(parts, "BYSECOND="+joinInts(expr.secondList))
+	}
+	return parts, lastYear, nil
+}
+
+func joinInts(list []int) string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+/******************************************************************************/
+
+// ParseRRule returns a new Expression pointer built from an iCalendar
+// recurrence rule as defined by RFC 5545, with or without its `RRULE:` prefix.
+//
+// The components which a rule takes from its DTSTART are taken from midnight
+// on the first of January. COUNT, INTERVAL other than 1, BYSETPOS, BYYEARDAY,
+// BYWEEKNO and UNTIL other than on the 31st of December cannot be represented
+// and an explanatory error is returned for them.
+func ParseRRule(rule string) (*Expression, error) {
+	var expr = Expression{
+		expression:       rule,
+		bothDaysRequired: true,
+	}
+
+	freq := -1
+	by := make(map[string]string)
+	lastYear := 0
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:"), ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("rrule: syntax error in '%s'", part)
+		}
+		switch name {
+		case "FREQ":
+			for i, f := range rruleFrequencies {
+				if f == value {
+					freq = i
+				}
+			}
+			if freq < 0 {
+				return nil, fmt.Errorf("rrule: unknown frequency '%s'", value)
+			}
+		case "INTERVAL":
+			if value != "1" {
+				return nil, fmt.Errorf("rrule: INTERVAL=%s cannot be represented", value)
+			}
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, err
+			}
+			if until.Month() != time.December || until.Day() != 31 {
+				return nil, fmt.Errorf("rrule: UNTIL=%s is not the end of a year", value)
+			}
+			lastYear = until.Year()
+		case "WKST":
+			// weeks are never spanned
+		case "BYSECOND", "BYMINUTE", "BYHOUR", "BYDAY", "BYMONTHDAY", "BYMONTH":
+			by[name] = value
+		default:
+			return nil, fmt.Errorf("rrule: %s cannot be represented", name)
+		}
+	}
+	if freq < 0 {
+		return nil, fmt.Errorf("rrule: missing FREQ")
+	}
+
+	// Components finer than the frequency default to those of DTSTART
+	fields := []struct {
+		name    string
+		freq    int
+		first   string
+		handler func(string) error
+	}{
+		{"BYSECOND", freqSecondly, "0", expr.secondFieldHandler},
+		{"BYMINUTE", freqMinutely, "0", expr.minuteFieldHandler},
+		{"BYHOUR", freqHourly, "0", expr.hourFieldHandler},
+		{"BYMONTH", freqMonthly, "1", expr.monthFieldHandler},
+	}
+	for _, field := range fields {
+		value, found := by[field.name]
+		if !found {
+			value = "*"
+			if freq > field.freq {
+				value = field.first
+			}
+			if field.name == "BYMONTH" && (by["BYDAY"] != "" || by["BYMONTHDAY"] != "") {
+				value = "*"
+			}
+		}
+		if err := field.handler(value); err != nil {
+			return nil, fmt.Errorf("rrule: %s", err)
+		}
+	}
+
+	// days
+	_ = expr.domFieldHandler("*")
+	_ = expr.dowFieldHandler("*")
+	if value, found := by["BYMONTHDAY"]; found {
+		expr.daysOfMonthRestricted = true
+		expr.daysOfMonth = make(map[int]bool)
+		expr.daysFromEndOfMonth = make(map[int]bool)
+		for _, s := range strings.Split(value, ",") {
+			v, err := strconv.Atoi(s)
+			switch {
+			case err != nil || v == 0 || v < -domDescriptor.max || v > domDescriptor.max:
+				return nil, fmt.Errorf("rrule: syntax error in BYMONTHDAY: '%s'", s)
+			case v > 0:
+				populateOne(expr.daysOfMonth, v)
+			default:
+				populateOne(expr.daysFromEndOfMonth, -v)
+			}
+		}
+	} else if _, found := by["BYDAY"]; !found && freq >= freqMonthly {
+		_ = expr.domFieldHandler("1")
+	}
+	if value, found := by["BYDAY"]; found {
+		expr.daysOfWeekRestricted = true
+		expr.daysOfWeek = make(map[int]bool)
+		for _, s := range strings.Split(value, ",") {
+			if len(s) < 2 {
+				return nil, fmt.Errorf("rrule: syntax error in BYDAY: '%s'", s)
+			}
+			dow := -1
+			for i, name := range rruleWeekdays {
+				if name == s[len(s)-2:] {
+					dow = i
+				}
+			}
+			if dow < 0 {
+				return nil, fmt.Errorf("rrule: syntax error in BYDAY: '%s'", s)
+			}
+			if len(s) == 2 {
+				populateOne(expr.daysOfWeek, dow)
+				continue
+			}
+			if freq < freqMonthly || freq == freqYearly && by["BYMONTH"] == "" {
+				return nil, fmt.Errorf("rrule: BYDAY=%s needs a monthly frequency", s)
+			}
+			ordinal, err := strconv.Atoi(s[:len(s)-2])
+			switch {
+			case err != nil:
+				return nil, fmt.Errorf("rrule: syntax error in BYDAY: '%s'", s)
+			case ordinal == -1:
+				populateOne(expr.lastWeekDaysOfWeek, dow)
+			case ordinal >= 1 && ordinal <= 5:
+				populateOne(expr.specificWeekDaysOfWeek, (ordinal-1)*7+dow)
+			default:
+				return nil, fmt.Errorf("rrule: BYDAY=%s cannot be represented", s)
+			}
+		}
+	} else if freq == freqWeekly {
+		return nil, fmt.Errorf("rrule: weekly frequency needs BYDAY")
+	}
+
+	// years
+	expr.yearList = yearDescriptor.defaultList
+	if lastYear != 0 {
+		if err := expr.yearFieldHandler(fmt.Sprintf("%d..%d", yearDescriptor.min, lastYear)); err != nil {
+			return nil, fmt.Errorf("rrule: %s", err)
+		}
+	}
+	return &expr, nil
+}
+
+func parseRRuleTime(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("rrule: invalid date '%s'", s)
+}
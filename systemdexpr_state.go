@@ -0,0 +1,121 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/******************************************************************************/
+
+// A StateStore records when each timer last triggered, so that the elapses
+// missed while a program was not running can be caught up, as systemd does
+// for `Persistent=true` timers.
+type StateStore interface {
+	// LastTrigger returns the zero time if the timer never triggered.
+	LastTrigger(timer string) (time.Time, error)
+	SetLastTrigger(timer string, t time.Time) error
+}
+
+// A Schedule returns the first elapse following a time instant, or the zero
+// time if there is none. *Expression is a Schedule.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// MissedElapse returns the first elapse of the schedule after the last
+// trigger and up to now, which a persistent timer catches up right away, or
+// the zero time if there is none. A timer which never triggered missed none.
+func MissedElapse(schedule Schedule, last, now time.Time) time.Time {
+	if last.IsZero() {
+		return time.Time{}
+	}
+	missed := schedule.Next(last)
+	if missed.IsZero() || missed.After(now) {
+		return time.Time{}
+	}
+	return missed
+}
+
+// CatchUp tells whether the expression elapsed after the last trigger and
+// up to now, in which case the timer triggers right away, and returns the
+// next elapse following now. A timer which never triggered is not caught up.
+func (expr *Expression) CatchUp(last, now time.Time) (bool, time.Time) {
+	return !MissedElapse(expr, last, now).IsZero(), expr.Next(now)
+}
+
+/******************************************************************************/
+
+// A MemoryStateStore is a StateStore which forgets everything when the
+// program exits.
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+// NewMemoryStateStore returns an empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{times: make(map[string]time.Time)}
+}
+
+func (s *MemoryStateStore) LastTrigger(timer string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.times[timer], nil
+}
+
+func (s *MemoryStateStore) SetLastTrigger(timer string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times[timer] = t
+	return nil
+}
+
+/******************************************************************************/
+
+// A FileStateStore keeps the last trigger of each timer as the modification
+// time of a `stamp-<timer>` file of a directory, like systemd does in
+// `/var/lib/systemd/timers`.
+type FileStateStore struct {
+	Dir string
+}
+
+func (s FileStateStore) path(timer string) (string, error) {
+	if timer == "" || filepath.Base(timer) != timer {
+		return "", fmt.Errorf("state: invalid timer name '%s'", timer)
+	}
+	return filepath.Join(s.Dir, "stamp-"+timer), nil
+}
+
+func (s FileStateStore) LastTrigger(timer string) (time.Time, error) {
+	path, err := s.path(timer)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func (s FileStateStore) SetLastTrigger(timer string, t time.Time) error {
+	path, err := s.path(timer)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, t, t)
+}
@@ -3,9 +3,16 @@ package systemdexpr
 /******************************************************************************/
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,6 +21,17 @@ import (
 
 /******************************************************************************/
 
+// mustParseSystemd parses an expression with the day rule of systemd.
+func mustParseSystemd(systemdLine string) *Expression {
+	expr, err := ParseWithOptions(systemdLine, Options{MatchBothDays: true})
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+/******************************************************************************/
+
 type systemdNormTest struct {
 	denormExp string
 	normExp   string
@@ -51,6 +69,9 @@ var systemdNormTests = []systemdNormTest{
 	{"yearly", "*-01-01 00:00:00"},
 	{"annually", "*-01-01 00:00:00"},
 	{"*:2/3", "*-*-* *:02/3:00"},
+	{"quarterly", "*-01,04,07,10-01 00:00:00"},
+	{"Mon *-05~07/1", "Mon *-05~07/1 00:00:00"},
+	{"*-*~05..01/3", "*-*~05..01/3 00:00:00"},
 }
 
 /******************************************************************************/
@@ -434,6 +455,663 @@ func TestParseSystemd(t *testing.T) {
 	}
 }
 
+func TestString(t *testing.T) {
+	for _, test := range systemdNormTests {
+		assert.Equalf(t, test.normExp, MustParse(test.denormExp).String(), "normalized form of %q", test.denormExp)
+		assert.Equalf(t, test.normExp, MustParse(test.normExp).String(), "normalized form of %q", test.normExp)
+	}
+}
+
+/******************************************************************************/
+
+func TestParseWithOptions(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	from := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+
+	// default location when no zone is given
+	expr, err := ParseWithOptions("*-*-* 05:40", Options{Location: loc})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, time.January, 4, 5, 40, 0, 0, loc), expr.Next(from))
+
+	// a zone in the expression wins over the default one
+	expr, err = ParseWithOptions("*-*-* 05:40 UTC", Options{Location: loc})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, time.January, 4, 5, 40, 0, 0, time.UTC), expr.Next(from))
+
+	// strict systemd syntax
+	for _, line := range []string{"FRI-SAT 00:00", "Fri#2 00:00", "*-*-L 00:00", "*-*-15W 00:00", "*-*-? 00:00", "*-jan-01 00:00", "*-?-01 00:00"} {
+		_, err = Parse(line)
+		assert.NoErrorf(t, err, "lenient parse of %q", line)
+		_, err = ParseWithOptions(line, Options{Strict: true})
+		assert.Errorf(t, err, "strict parse of %q", line)
+	}
+	_, err = ParseWithOptions("Fri..Sat *-*-1..5 00:00", Options{Strict: true})
+	assert.NoError(t, err)
+	_, err = ParseWithOptions("*-1x-01 00:00", Options{Strict: true})
+	assert.Error(t, err)
+	_, err = ParseWithOptions("*-Mai,Juni-01 00:00", Options{Strict: true, Locale: German})
+	assert.NoError(t, err)
+
+	// two-digit years
+	cases := []struct {
+		pattern  string
+		mode     TwoDigitYearMode
+		expected time.Time
+	}{
+		{"12-10-15", TwoDigitYear2000, time.Time{}},
+		{"25-10-15", TwoDigitYear2000, time.Date(2025, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		{"85-10-15", TwoDigitYear2000, time.Date(2085, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		{"85-10-15", TwoDigitYear1970, time.Time{}},
+		{"69-10-15", TwoDigitYear1970, time.Date(2069, time.October, 15, 0, 0, 0, 0, time.UTC)},
+		{"18..20,25-10-15", TwoDigitYear1970, time.Date(2019, time.October, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		expr, err := ParseWithOptions(c.pattern, Options{TwoDigitYears: c.mode})
+		require.NoError(t, err)
+		assert.Equalf(t, c.expected, expr.Next(from), "next time of %q", c.pattern)
+	}
+
+	// aliases
+	_, err = ParseWithOptions("hourly", Options{NoAliases: true})
+	assert.Error(t, err)
+	_, err = ParseWithOptions("*-*-* *:00:00", Options{NoAliases: true})
+	assert.NoError(t, err)
+}
+
+/******************************************************************************/
+
+func TestNextLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	// 23:30 in Berlin, already the next day in Tokyo
+	from := time.Date(2019, time.January, 4, 22, 30, 0, 0, time.UTC)
+
+	// without a zone, the expression follows the location of fromTime
+	assert.Equal(t, time.Date(2019, time.January, 5, 9, 0, 0, 0, tokyo), MustParse("*-*-* 09:00").Next(from.In(tokyo)))
+	assert.Equal(t, time.Date(2019, time.January, 5, 9, 0, 0, 0, time.UTC), MustParse("*-*-* 09:00").Next(from))
+
+	// with a zone, fromTime is converted to it and so is the result
+	for _, loc := range []*time.Location{time.UTC, berlin, tokyo} {
+		next := MustParse("*-*-05 00:00 Europe/Berlin").Next(from.In(loc))
+		assert.Equal(t, time.Date(2019, time.January, 5, 0, 0, 0, 0, berlin), next, "from %s", loc)
+		assert.Equal(t, berlin, next.Location())
+	}
+
+	// a name missing from the time zone database is a zone at UTC+0
+	next := MustParse("*-*-* 09:00 Nowhere").Next(from)
+	assert.Equal(t, "Nowhere", next.Location().String())
+	_, offset := next.Zone()
+	assert.Equal(t, 0, offset)
+	assert.True(t, next.Equal(time.Date(2019, time.January, 5, 9, 0, 0, 0, time.UTC)))
+
+	_, err = ParseWithOptions("*-*-* 09:00 Nowhere", Options{Strict: true})
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "Nowhere", parseErr.Token)
+	_, err = ParseWithOptions("*-*-* 09:00 Europe/Berlin", Options{Strict: true})
+	assert.NoError(t, err)
+}
+
+/******************************************************************************/
+
+func TestParseCron(t *testing.T) {
+	initTime := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+
+	// cron and systemd spelling of the same schedule
+	equivalents := []struct {
+		cron    string
+		systemd string
+	}{
+		{"0 9 * * 1-5", "Mon..Fri 09:00"},
+		{"*/15 * * * *", "*:0/15"},
+		{"30 8 1,15 * *", "*-*-01,15 08:30"},
+		{"0 0 1 jan *", "*-01-01 00:00"},
+		{"0 0 * * SUN 2025", "Sun 2025-*-* 00:00"},
+		{"15 30 2 * * * *", "02:30:15"},
+		{"@hourly", "hourly"},
+		{"@weekly", "Sun 00:00"},
+	}
+	for _, test := range equivalents {
+		cron := MustParseCron(test.cron)
+		systemd := MustParse(test.systemd)
+		assert.Equalf(t, systemd.NextN(initTime, 10), cron.NextN(initTime, 10), "next times of %q", test.cron)
+	}
+
+	cases := []struct {
+		pattern  string
+		expected []time.Time
+	}{
+		// last day of month
+		{"0 0 L * *", []time.Time{
+			time.Date(2019, time.January, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 28, 0, 0, 0, 0, time.UTC),
+		}},
+		// nearest workday to the 5th
+		{"0 0 5W * *", []time.Time{
+			time.Date(2019, time.February, 5, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.March, 5, 0, 0, 0, 0, time.UTC),
+		}},
+		// third friday
+		{"0 0 * * 5#3", []time.Time{
+			time.Date(2019, time.January, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 15, 0, 0, 0, 0, time.UTC),
+		}},
+		// either the 10th or a monday
+		{"0 0 10 * 1", []time.Time{
+			time.Date(2019, time.January, 7, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.January, 10, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.January, 14, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+	for _, c := range cases {
+		assert.Equalf(t, c.expected, MustParseCron(c.pattern).NextN(initTime, uint(len(c.expected))), "next times of %q", c.pattern)
+	}
+
+	for _, line := range []string{"* * * *", "* * * * * * * *", "61 * * * *", "* * 32 * *", "* * * * 8"} {
+		_, err := ParseCron(line)
+		assert.Errorf(t, err, "parse of %q", line)
+	}
+}
+
+/******************************************************************************/
+
+func TestSystemdBothDays(t *testing.T) {
+	// first monday of the month: systemd wants both the weekday and the date
+	expected := []time.Time{
+		time.Date(2019, time.January, 7, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.February, 4, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.March, 4, 12, 0, 0, 0, time.UTC),
+	}
+	from := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, expected, mustParseSystemd("Mon *-*-01..07 12:00").NextN(from, 3))
+
+	// Parse and cron are happy with either of them
+	expected = []time.Time{
+		time.Date(2019, time.January, 4, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.January, 5, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.January, 6, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.January, 7, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, time.January, 14, 12, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, expected, MustParse("Mon *-*-01..07 12:00").NextN(from, 5))
+	assert.Equal(t, expected, MustParseCron("0 12 1-7 * MON").NextN(from, 5))
+}
+
+/******************************************************************************/
+
+func TestWeekdaySpanToSunday(t *testing.T) {
+	// friday
+	from := time.Date(2019, time.January, 4, 13, 0, 0, 0, time.UTC)
+	cases := []struct {
+		pattern string
+		days    []int
+	}{
+		{"Sat..Sun 12:00", []int{5, 6, 12, 13}},
+		{"Fri..Sun 12:00", []int{5, 6, 11, 12}},
+		{"Mon..Sun/2 12:00", []int{6, 7, 9, 11}},
+		{"Tue..Sun/2 12:00", []int{5, 8, 10, 12}},
+	}
+	for _, c := range cases {
+		var days []int
+		for _, next := range MustParse(c.pattern).NextN(from, uint(len(c.days))) {
+			days = append(days, next.Day())
+		}
+		assert.Equalf(t, c.days, days, "next days of %q", c.pattern)
+	}
+	assert.Equal(t, 5, MustParseCron("0 12 * * 6-7").Next(from).Day())
+	assert.Equal(t, 6, MustParseCron("0 12 * * 7").Next(from.AddDate(0, 0, 1)).Day())
+}
+
+/******************************************************************************/
+
+func TestDaysFromEndOfMonth(t *testing.T) {
+	from := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		pattern  string
+		expected []time.Time
+	}{
+		{"*-*~01", []time.Time{
+			time.Date(2019, time.January, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 28, 0, 0, 0, 0, time.UTC),
+		}},
+		{"2020-02~03 12:00", []time.Time{
+			time.Date(2020, time.February, 27, 12, 0, 0, 0, time.UTC),
+			{},
+		}},
+		{"Mon *-05~07/1", []time.Time{
+			time.Date(2019, time.May, 27, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, time.May, 25, 0, 0, 0, 0, time.UTC),
+		}},
+		{"*-*~03..01", []time.Time{
+			time.Date(2019, time.January, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.January, 30, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.January, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 26, 0, 0, 0, 0, time.UTC),
+		}},
+		{"*-*~05..01/3", []time.Time{
+			time.Date(2019, time.January, 27, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.January, 30, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 24, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, time.February, 27, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+	for _, c := range cases {
+		starting := from
+		expr := mustParseSystemd(c.pattern)
+		for _, next := range c.expected {
+			n := expr.Next(starting)
+			assert.Equalf(t, next, n, "next time of %q from %v", c.pattern, starting)
+			starting = n
+		}
+	}
+}
+
+/******************************************************************************/
+
+func TestToSystemd(t *testing.T) {
+	initTime := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		cron    string
+		systemd string
+		lossy   bool
+	}{
+		{"0 9 * * 1-5", "Mon..Fri *-*-* 09:00:00", false},
+		{"*/15 * * * *", "*-*-* *:00/15:00", false},
+		{"30 8 1,15 * *", "*-*-01,15 08:30:00", false},
+		{"0 0 1 1 *", "*-01-01 00:00:00", false},
+		{"0 0 * * 0,6 2025", "Sat,Sun 2025-*-* 00:00:00", false},
+		{"5 4 * * sun", "Sun *-*-* 04:05:00", false},
+		{"0 22 * * 1-3,5", "Mon..Wed,Fri *-*-* 22:00:00", false},
+		{"0 0 L * *", "*-*~01 00:00:00", false},
+		{"0 0 * * 5#3", "Fri *-*-15..21 00:00:00", false},
+		{"0 0 * * 5L", "Fri *-*~07..01 00:00:00", false},
+		{"0 0 * * 5#1,5#3", "Fri *-*-01..07,15..21 00:00:00", false},
+		{"0 0 * * 1#1,5#1", "Mon,Fri *-*-01..07 00:00:00", false},
+		{"0 0 * * 1#5", "Mon *-*-29..31 00:00:00", false},
+		// closest approximations
+		{"0 0 15W * *", "*-*-15 00:00:00", true},
+		{"0 0 LW * *", "*-*~01 00:00:00", true},
+		{"0 0 1,15W * *", "*-*-01,15 00:00:00", true},
+		{"0 0 * * 1#1,5#3", "Mon,Fri *-*-01..07,15..21 00:00:00", true},
+		{"0 0 * * 1L,5#3", "Mon,Fri *-*-* 00:00:00", true},
+		{"0 0 * * 1,5#3", "Mon,Fri *-*-* 00:00:00", true},
+		{"0 0 10 * 1", "Mon *-*-10 00:00:00", true},
+	}
+	for _, c := range cases {
+		systemd, err := ToSystemd(c.cron)
+		assert.Equalf(t, c.systemd, systemd, "conversion of %q", c.cron)
+		if !c.lossy {
+			require.NoErrorf(t, err, "conversion of %q", c.cron)
+			assert.Equalf(t, MustParseCron(c.cron).NextN(initTime, 20), mustParseSystemd(systemd).NextN(initTime, 20), "next times of %q", c.cron)
+			continue
+		}
+		var lossy *LossyError
+		assert.ErrorAsf(t, err, &lossy, "conversion of %q", c.cron)
+	}
+}
+
+/******************************************************************************/
+
+func TestToCron(t *testing.T) {
+	initTime := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		systemd string
+		cron    string
+		lossy   []string
+	}{
+		{"Mon..Fri 09:00", "0 9 * * 1-5", nil},
+		{"*:0/15", "*/15 * * * *", nil},
+		{"*-*-01,15 08:30", "30 8 1,15 * *", nil},
+		{"quarterly", "0 0 1 */3 *", nil},
+		{"daily", "0 0 * * *", nil},
+		{"*-*-* *:*:*", "* * * * *", []string{"seconds"}},
+		{"2025-*-* 00:00", "0 0 * * *", []string{"years"}},
+		{"*-*~01 00:00", "0 0 * * *", []string{"days counted from the end of month"}},
+		{"Mon *-*-01..07 00:00", "0 0 1-7 * 1", []string{"day of month and day of week"}},
+		{"daily UTC", "0 0 * * *", []string{"time zone"}},
+	}
+	for _, c := range cases {
+		cron, err := ToCron(mustParseSystemd(c.systemd))
+		assert.Equalf(t, c.cron, cron, "conversion of %q", c.systemd)
+		if c.lossy == nil {
+			require.NoErrorf(t, err, "conversion of %q", c.systemd)
+			assert.Equalf(t, mustParseSystemd(c.systemd).NextN(initTime, 20), MustParseCron(cron).NextN(initTime, 20), "next times of %q", c.systemd)
+			continue
+		}
+		var lossy *LossyError
+		if assert.ErrorAsf(t, err, &lossy, "conversion of %q", c.systemd) {
+			assert.Equal(t, c.lossy, lossy.Components)
+		}
+	}
+}
+
+/******************************************************************************/
+
+func TestRRule(t *testing.T) {
+	initTime := time.Date(2019, time.January, 4, 1, 0, 0, 0, time.UTC)
+	cases := []struct {
+		systemd string
+		rrule   string
+	}{
+		{"*-*-* *:*:*", "FREQ=SECONDLY"},
+		{"*:0/15", "FREQ=HOURLY;BYMINUTE=0,15,30,45;BYSECOND=0"},
+		{"hourly", "FREQ=HOURLY;BYMINUTE=0;BYSECOND=0"},
+		{"daily", "FREQ=DAILY;BYHOUR=0;BYMINUTE=0;BYSECOND=0"},
+		{"Mon..Fri 09:30", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9;BYMINUTE=30;BYSECOND=0"},
+		{"*-*-01,15 08:30:15", "FREQ=MONTHLY;BYMONTHDAY=1,15;BYHOUR=8;BYMINUTE=30;BYSECOND=15"},
+		{"*-*~01 23:00", "FREQ=MONTHLY;BYMONTHDAY=-1;BYHOUR=23;BYMINUTE=0;BYSECOND=0"},
+		{"quarterly", "FREQ=YEARLY;BYMONTH=1,4,7,10;BYMONTHDAY=1;BYHOUR=0;BYMINUTE=0;BYSECOND=0"},
+		{"Mon *-*-01..07 12:00", "FREQ=MONTHLY;BYMONTHDAY=1,2,3,4,5,6,7;BYDAY=MO;BYHOUR=12;BYMINUTE=0;BYSECOND=0"},
+		{"*-*-* 0..2,4..5:10:00", "FREQ=DAILY;BYHOUR=0,1,2,4,5;BYMINUTE=10;BYSECOND=0"},
+		{"1970..2021-02-29", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;BYHOUR=0;BYMINUTE=0;BYSECOND=0;UNTIL=20211231T235959"},
+	}
+	for _, c := range cases {
+		expr := mustParseSystemd(c.systemd)
+		rrule, err := ToRRule(expr)
+		require.NoErrorf(t, err, "conversion of %q", c.systemd)
+		assert.Equalf(t, c.rrule, rrule, "conversion of %q", c.systemd)
+
+		back, err := ParseRRule(rrule)
+		require.NoErrorf(t, err, "parse of %q", rrule)
+		assert.Equalf(t, expr.NextN(initTime, 25), back.NextN(initTime, 25), "next times of %q", rrule)
+	}
+
+	// cron weekday forms
+	for _, cron := range []string{"0 0 * * 5#3", "30 6 * 3,9 1L", "0 0 L * *"} {
+		expr := MustParseCron(cron)
+		rrule, err := ToRRule(expr)
+		require.NoErrorf(t, err, "conversion of %q", cron)
+		back, err := ParseRRule(rrule)
+		require.NoErrorf(t, err, "parse of %q", rrule)
+		assert.Equalf(t, expr.NextN(initTime, 25), back.NextN(initTime, 25), "next times of %q", rrule)
+	}
+
+	// rules written by others
+	equivalents := []struct {
+		rrule   string
+		systemd string
+	}{
+		{"RRULE:FREQ=WEEKLY;BYDAY=SA,SU;BYHOUR=10;WKST=MO", "Sat,Sun 10:00"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;BYHOUR=17;BYMINUTE=0", "Fri *-*~07..01 17:00"},
+		{"FREQ=MONTHLY;BYDAY=2TU", "Tue *-*-08..14 00:00"},
+		{"FREQ=YEARLY", "*-01-01 00:00"},
+		{"FREQ=HOURLY;BYMINUTE=0,30", "*:00,30"},
+	}
+	for _, test := range equivalents {
+		expr, err := ParseRRule(test.rrule)
+		require.NoErrorf(t, err, "parse of %q", test.rrule)
+		assert.Equalf(t, mustParseSystemd(test.systemd).NextN(initTime, 25), expr.NextN(initTime, 25), "next times of %q", test.rrule)
+	}
+
+	for _, systemd := range []string{"daily UTC", "2020..2025-*-* 00:00", "2020,2025-*-* 00:00", "Mon *-*-01..07 12:00"} {
+		_, err := ToRRule(MustParse(systemd))
+		assert.Errorf(t, err, "conversion of %q", systemd)
+	}
+	for _, cron := range []string{"0 0 15W * *", "0 0 10 * 1"} {
+		_, err := ToRRule(MustParseCron(cron))
+		assert.Errorf(t, err, "conversion of %q", cron)
+	}
+	for _, rrule := range []string{"BYHOUR=1", "FREQ=DAILY;COUNT=3", "FREQ=DAILY;INTERVAL=2", "FREQ=WEEKLY",
+		"FREQ=YEARLY;BYDAY=1MO", "FREQ=MONTHLY;BYDAY=-2MO", "FREQ=DAILY;UNTIL=20200615", "FREQ=DAILY;BYHOUR=24"} {
+		_, err := ParseRRule(rrule)
+		assert.Errorf(t, err, "parse of %q", rrule)
+	}
+}
+
+/******************************************************************************/
+
+func TestWriteCalendar(t *testing.T) {
+	from := time.Date(2019, time.January, 4, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	var b strings.Builder
+	err := WriteCalendar(&b, from, to, CalendarEntry{Label: "backup; nightly", Expression: MustParse("daily"), Duration: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//systemdexpr//systemdexpr//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VEVENT",
+		"UID:1546560000-0@systemdexpr",
+		"DTSTAMP:20190104T000000Z",
+		"DTSTART:20190104T000000Z",
+		"DTEND:20190104T010000Z",
+		"RRULE:FREQ=DAILY;BYHOUR=0;BYMINUTE=0;BYSECOND=0;UNTIL=20190111T000000Z",
+		"SUMMARY:backup\\; nightly",
+		"DESCRIPTION:*-*-* 00:00:00",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), b.String())
+
+	// not in UTC, every elapse is an event
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	b.Reset()
+	err = WriteCalendar(&b, from.In(loc), to.In(loc),
+		CalendarEntry{Expression: MustParse("Mon,Wed 09:30")},
+		CalendarEntry{Expression: MustParseCron("0 0 15W * *")})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(b.String(), "BEGIN:VEVENT"))
+	assert.NotContains(t, b.String(), "RRULE")
+	assert.Contains(t, b.String(), "DTSTART:20190107T173000Z\r\n")
+	assert.Contains(t, b.String(), "DTSTART:20190109T173000Z\r\n")
+
+	// an elapse earlier within the second of `from` is not in the window
+	b.Reset()
+	err = WriteCalendar(&b, from.Add(time.Second/2), to, CalendarEntry{Expression: MustParse("daily")})
+	require.NoError(t, err)
+	assert.Contains(t, b.String(), "DTSTART:20190105T000000Z\r\n")
+	b.Reset()
+	err = WriteCalendar(&b, from.Add(3*24*time.Hour+17*time.Hour+30*time.Minute+time.Second/2).In(loc), to.In(loc),
+		CalendarEntry{Expression: MustParse("Mon,Wed 09:30")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(b.String(), "BEGIN:VEVENT"))
+	assert.Contains(t, b.String(), "DTSTART:20190109T173000Z\r\n")
+
+	// long lines are folded
+	b.Reset()
+	err = WriteCalendar(&b, from, to, CalendarEntry{Label: strings.Repeat("é", 80), Expression: MustParse("Mon 12:00 UTC")})
+	require.NoError(t, err)
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Contains(t, strings.ReplaceAll(b.String(), "\r\n ", ""), "SUMMARY:"+strings.Repeat("é", 80)+"\r\n")
+
+	// too many events, nothing is written even past a first large entry
+	b.Reset()
+	err = WriteCalendar(&b, from.In(loc), to.In(loc),
+		CalendarEntry{Expression: MustParse("*:00")},
+		CalendarEntry{Expression: MustParse("*:*:*")})
+	assert.Error(t, err)
+	assert.Empty(t, b.String())
+}
+
+/******************************************************************************/
+
+func TestDescribe(t *testing.T) {
+	for _, test := range systemdNormTests {
+		assert.Equalf(t, Describe(MustParse(test.normExp)), Describe(MustParse(test.denormExp)), "description of %q", test.denormExp)
+	}
+
+	cases := []struct {
+		expr        *Expression
+		description string
+	}{
+		{mustParseSystemd("Mon..Fri *-*-01..07 09:00"), "at 09:00:00 on Monday through Friday, on days 1–7 of every month"},
+		{MustParse("Mon..Fri *-*-01..07 09:00"), "at 09:00:00 on days 1–7 of every month or on Monday through Friday"},
+		{MustParse("daily"), "at 00:00:00 every day"},
+		{MustParse("hourly"), "every hour"},
+		{MustParse("*:0/15"), "every 15 minutes"},
+		{MustParse("*:2/3"), "every 3 minutes from minute 2"},
+		{MustParse("*-*-* *:*:0/5"), "every 5 seconds"},
+		{MustParse("*-*-* 0..2,4..5,7..23:10:00"), "at minute 10 during hours 0–2, 4, 5 and 7–23 every day"},
+		{MustParse("*-*-* 00:17..43"), "at minutes 17–43 during hour 0 every day"},
+		{MustParse("Mon,Sun 12-*-* 2,1:23"), "at 01:23:00 and 02:23:00 on Monday and Sunday in 2012"},
+		{MustParse("quarterly"), "at 00:00:00 on day 1 of January, April, July and October"},
+		{MustParse("*-*-1/3"), "at 00:00:00 every 3 days from day 1 of every month"},
+		{MustParse("*-*~01 23:00"), "at 23:00:00 on the last day of every month"},
+		{MustParse("*-*~03"), "at 00:00:00 on the third to last day of every month"},
+		{mustParseSystemd("Mon *-05~07/1"), "at 00:00:00 on Monday, on the last 7 days of May"},
+		{MustParse("2019..2023-02-05"), "at 00:00:00 on day 5 of February in 2019–2023"},
+		{MustParse("Sat,Sun *-06..08-* 08:05:40 UTC"), "at 08:05:40 on Saturday and Sunday in June through August (UTC)"},
+		{MustParseCron("0 0 15W * *"), "at 00:00:00 on the workday nearest day 15 of every month"},
+		{MustParseCron("0 0 LW * *"), "at 00:00:00 on the last workday of every month"},
+		{MustParseCron("0 0 L * *"), "at 00:00:00 on the last day of every month"},
+		{MustParseCron("0 0 * * 5#3,1L"), "at 00:00:00 on the third Friday and the last Monday"},
+		{MustParseCron("0 0 10 * 1"), "at 00:00:00 on day 10 of every month or on Monday"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.description, Describe(c.expr))
+	}
+}
+
+/******************************************************************************/
+
+func TestDescribeIn(t *testing.T) {
+	cases := []struct {
+		locale      Locale
+		expr        string
+		description string
+	}{
+		{German, "Mon..Fri *-*-01..07 09:00", "um 09:00:00 am Montag bis Freitag, an den Tagen 1–7 jedes Monats"},
+		{German, "*:0/15", "alle 15 Minuten"},
+		{German, "Sat,Sun *-06..08-* 08:05:40", "um 08:05:40 am Samstag und Sonntag im Juni bis August"},
+		{Russian, "Mon,Wed 09:00", "в 09:00:00 в понедельник и среду"},
+		{Russian, "*:0/5", "каждые 5 минут"},
+		{Russian, "*:0/2", "каждые 2 минуты"},
+		{Russian, "*-*-* *:*:0/21", "каждую 21 секунду"},
+		{Russian, "quarterly", "в 00:00:00 в день 1 января, апреля, июля и октября"},
+		{Spanish, "Mon..Fri *-*-01..07 09:00", "a las 09:00:00 el lunes a viernes, los días 1–7 de cada mes"},
+		{Spanish, "*-*~01 23:00", "a las 23:00:00 el último día de cada mes"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.description, DescribeIn(mustParseSystemd(c.expr), c.locale))
+	}
+
+	expr, err := ParseCron("0 0 * * 5#2")
+	require.NoError(t, err)
+	assert.Equal(t, "a las 00:00:00 el segundo viernes", DescribeIn(expr, Spanish))
+	assert.Equal(t, "um 00:00:00 am zweiten Freitag", DescribeIn(expr, German))
+
+	// the preposition is not repeated for each weekday
+	expr, err = ParseCron("0 0 * * 1,5#2,3L")
+	require.NoError(t, err)
+	assert.Equal(t, "at 00:00:00 on Monday, the second Friday and the last Wednesday", DescribeIn(expr, English))
+	assert.Equal(t, "um 00:00:00 am Montag, zweiten Freitag und letzten Mittwoch", DescribeIn(expr, German))
+	assert.Equal(t, "в 00:00:00 в понедельник, пятницу 2-й недели и среду последней недели", DescribeIn(expr, Russian))
+	assert.Equal(t, "a las 00:00:00 el lunes, segundo viernes y último miércoles", DescribeIn(expr, Spanish))
+}
+
+/******************************************************************************/
+
+func TestParseNatural(t *testing.T) {
+	from := time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		phrase string
+		equiv  string
+	}{
+		{"every 15 minutes", "*:0/15"},
+		{"Every second", "*:*:*"},
+		{"every 6 hours", "0/6:00"},
+		{"weekdays at 9:30", "Mon..Fri 09:30"},
+		{"every weekday at 9am", "Mon..Fri 09:00"},
+		{"first Monday of the month at noon", "Mon *-*-01..07 12:00"},
+		{"second Tuesday of every month at 6:15 pm", "Tue *-*-08..14 18:15"},
+		{"last Friday of the month", "Fri *-*~07..01"},
+		{"last day of every quarter", "*-03,06,09,12~01"},
+		{"first day of the year at midnight", "*-01-01 00:00"},
+		{"on the 15th at 8:00 and 20:00", "*-*-15 08,20:00"},
+		{"every Monday and Wednesday at 7:00", "Mon,Wed 07:00"},
+		{"Saturday through Sunday at 10:00 in June through August", "Sat..Sun *-06..08-* 10:00"},
+		{"monthly", "monthly"},
+		{"weekly", "weekly"},
+		{"quarterly", "quarterly"},
+		{"hourly", "hourly"},
+		{"every 24 hours", "daily"},
+		{"every 60 minutes", "hourly"},
+		{"2nd Tuesday of the month", "Tue *-*-08..14"},
+	}
+	for _, c := range cases {
+		expr, err := ParseNatural(c.phrase)
+		require.NoErrorf(t, err, "parsing %q", c.phrase)
+		assert.Equalf(t, mustParseSystemd(c.equiv).NextN(from, 10), expr.NextN(from, 10), "elapses of %q", c.phrase)
+	}
+
+	failures := []struct {
+		phrase     string
+		word       string
+		suggestion string
+	}{
+		{"every mondya at 9:00", "mondya", "monday"},
+		{"weekdays at 9:30 in Febuary", "febuary", "february"},
+		{"weekdays in 9:30", "9:30", "in January"},
+		{"evry day", "evry", "every"},
+		{"every", "", "every 15 minutes"},
+		{"at 25:00", "25:00", ""},
+		{"every 5 minutes at 9:00", "", "every 15 minutes"},
+		{"at 9:00 and 10:30", "", "at 9:00 and 17:00"},
+		{"", "", "every day at 9:00"},
+		{"every second Tuesday", "second", "second tuesday of every month"},
+		{"every third Friday at 9:00", "third", "third friday of every month"},
+		{"every 61 minutes", "", ""},
+		{"6th Monday", "6th", "first Monday"},
+		{"the", "", "every day at 9:00"},
+		{"and", "", "every day at 9:00"},
+		{"on the", "", "every day at 9:00"},
+	}
+	for _, c := range failures {
+		_, err := ParseNatural(c.phrase)
+		var natural *NaturalError
+		if assert.ErrorAsf(t, err, &natural, "parsing %q", c.phrase) {
+			assert.Equalf(t, c.word, natural.Word, "word of %q", c.phrase)
+			assert.Equalf(t, c.suggestion, natural.Suggestion, "suggestion for %q", c.phrase)
+		}
+	}
+	_, err := ParseNatural("every mondya")
+	assert.EqualError(t, err, "natural: unknown word 'mondya', did you mean 'monday'?")
+	_, err = ParseNatural("every 61 minutes")
+	var parseErr *ParseError
+	assert.ErrorAs(t, err, &parseErr)
+}
+
+/******************************************************************************/
+
+func TestParseLocaleNames(t *testing.T) {
+	from := time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		locale Locale
+		expr   string
+		equiv  string
+	}{
+		{German, "Mo..Fr *-Mai-* 09:00", "Mon..Fri *-05-* 09:00"},
+		{German, "Samstag,Sonntag 10:00", "Sat,Sun 10:00"},
+		{Russian, "Пн,Ср *-*-* 12:00", "Mon,Wed 12:00"},
+		{Spanish, "lunes..viernes *-marzo-01 08:00 Europe/Madrid", "Mon..Fri *-03-01 08:00 Europe/Madrid"},
+		// names within the time zone are left alone
+		{Spanish, "lunes 09:00 America/Santo_Domingo", "Mon 09:00 America/Santo_Domingo"},
+		{Spanish, "lunes America/Santo_Domingo", "Mon America/Santo_Domingo"},
+		{German, "Mo 09:00 Europe/Monaco", "Mon 09:00 Europe/Monaco"},
+	}
+	for _, c := range cases {
+		expr, err := ParseWithOptions(c.expr, Options{Locale: c.locale})
+		require.NoErrorf(t, err, "parsing %q", c.expr)
+		assert.Equalf(t, MustParse(c.equiv).NextN(from, 5), expr.NextN(from, 5), "elapses of %q", c.expr)
+	}
+
+	expr, err := ParseWithOptions("lunes 09:00 America/Santo_Domingo", Options{Locale: Spanish})
+	require.NoError(t, err)
+	assert.Equal(t, "America/Santo_Domingo", expr.Next(from).Location().String())
+
+	// names are only translated when asked to
+	_, err = ParseWithOptions("Montag 09:00", Options{Locale: English})
+	assert.Error(t, err)
+}
+
 /******************************************************************************/
 
 func TestZero(t *testing.T) {
@@ -502,6 +1180,534 @@ func TestNextN_every5min(t *testing.T) {
 	}
 }
 
+func TestBetween(t *testing.T) {
+	expr := MustParse("*:0/15")
+	from := time.Date(2013, time.September, 2, 8, 45, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	result := expr.Between(from, to)
+	require.Len(t, result, 4)
+	assert.Equal(t, from, result[0])
+	assert.Equal(t, to.Add(-15*time.Minute), result[3])
+
+	// adjacent windows share no elapse
+	assert.Equal(t, append(expr.Between(from, from.Add(30*time.Minute)), expr.Between(from.Add(30*time.Minute), to)...), result)
+	assert.Equal(t, result[1:], expr.Between(from.Add(time.Millisecond), to))
+	assert.Empty(t, expr.Between(to, from))
+	assert.Empty(t, MustParse("2012-*-* 00:00").Between(from, to))
+}
+
+func TestDaysOfMonth(t *testing.T) {
+	assert.Equal(t, []int{2, 9, 16, 23, 30}, MustParse("Mon 09:00").DaysOfMonth(2026, time.November))
+	assert.Equal(t, []int{30}, MustParse("*-*~01").DaysOfMonth(2026, time.November))
+	assert.Equal(t, []int{2}, MustParseCron("0 0 1W * *").DaysOfMonth(2026, time.November))
+	assert.Empty(t, MustParse("*-12-* 00:00").DaysOfMonth(2026, time.November))
+	assert.Empty(t, MustParse("2019-*-* 00:00").DaysOfMonth(2026, time.November))
+
+	assert.Nil(t, MustParse("daily").Location())
+	assert.Equal(t, "Europe/Berlin", MustParse("daily Europe/Berlin").Location().String())
+}
+
+/******************************************************************************/
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	timer := clock.NewTimer(time.Minute)
+	after := clock.After(2 * time.Minute)
+	stopped := clock.NewTimer(30 * time.Second)
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-timer.C():
+		t.Error("timer fired early")
+	default:
+	}
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.False(t, timer.Reset(time.Minute))
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(2*time.Minute), <-after)
+	assert.Equal(t, start.Add(2*time.Minute), <-timer.C())
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+
+	// a goroutine waiting on the clock
+	done := make(chan time.Time)
+	go func() { done <- <-clock.After(time.Hour) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	assert.Equal(t, start.Add(62*time.Minute), <-done)
+}
+
+func TestFakeClock_Jump(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	timer := clock.NewTimer(time.Minute)
+
+	clock.Jump(time.Hour)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+	assert.Equal(t, time.Duration(0), clock.Monotonic())
+	select {
+	case <-timer.C():
+		t.Error("timer fired on a jump")
+	default:
+	}
+
+	clock.Jump(-2 * time.Hour)
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(-59*time.Minute), <-timer.C())
+	assert.Equal(t, time.Minute, clock.Monotonic())
+}
+
+func TestFakeClock_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	clock := NewFakeClock(time.Date(2026, time.March, 29, 1, 30, 0, 0, loc))
+	expr := MustParse("*-*-* *:00,30")
+
+	var elapses []string
+	for i := 0; i < 3; i++ {
+		next := expr.Next(clock.Now())
+		clock.Advance(next.Sub(clock.Now()))
+		elapses = append(elapses, clock.Now().Format("15:04 MST"))
+	}
+	assert.Equal(t, []string{"03:00 CEST", "03:30 CEST", "04:00 CEST"}, elapses)
+}
+
+/******************************************************************************/
+
+func TestWaiter(t *testing.T) {
+	w := Waiter{MaxWait: 5 * time.Millisecond}
+	until := time.Now().Add(30 * time.Millisecond)
+	assert.Equal(t, WaitElapsed, w.Wait(context.Background(), until))
+	assert.False(t, time.Now().Before(until))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, WaitCanceled, w.Wait(ctx, time.Now().Add(time.Hour)))
+	elapse, err := w.WaitNext(ctx, MustParse("hourly").Next, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, elapse.IsZero())
+}
+
+func TestWaiter_Jump(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	w := Waiter{Clock: clock}
+	wait := func(until time.Time) <-chan WaitResult {
+		result := make(chan WaitResult, 1)
+		go func() { result <- w.Wait(context.Background(), until) }()
+		clock.BlockUntil(1)
+		return result
+	}
+
+	// a drift within the tolerance is not a jump
+	result := wait(start.Add(2 * time.Minute))
+	clock.Jump(500 * time.Millisecond)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, WaitElapsed, <-result)
+
+	// stepped forward, short of the instant
+	result = wait(start.Add(time.Hour))
+	clock.Jump(30 * time.Minute)
+	clock.Advance(time.Minute)
+	assert.Equal(t, WaitJumped, <-result)
+
+	// resumed from suspend, past the instant
+	result = wait(start.Add(time.Hour))
+	clock.Jump(2 * time.Hour)
+	clock.Advance(time.Minute)
+	assert.Equal(t, WaitElapsed, <-result)
+
+	// stepped backward
+	result = wait(clock.Now().Add(time.Hour))
+	clock.Jump(-time.Minute)
+	clock.Advance(time.Minute)
+	assert.Equal(t, WaitJumped, <-result)
+}
+
+func TestWaiter_WaitNext(t *testing.T) {
+	start := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	w := Waiter{Clock: clock}
+	elapses := make(chan time.Time, 1)
+	go func() {
+		elapse, err := w.WaitNext(context.Background(), MustParse("hourly").Next, start)
+		assert.NoError(t, err)
+		elapses <- elapse
+	}()
+
+	// 13:00 is recomputed as 11:00 once the clock is set back
+	clock.BlockUntil(1)
+	clock.Jump(-2 * time.Hour)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(58 * time.Minute)
+	clock.BlockUntil(1)
+	assert.Empty(t, elapses)
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(-time.Hour), <-elapses)
+
+	elapse, err := w.WaitNext(context.Background(), MustParse("2019-*-*").Next, start)
+	assert.NoError(t, err)
+	assert.True(t, elapse.IsZero())
+}
+
+/******************************************************************************/
+
+func TestCatchUp(t *testing.T) {
+	expr := MustParse("daily")
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	tomorrow := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		last time.Time
+		due  bool
+	}{
+		{time.Time{}, false},
+		{time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, time.October, 17, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, time.October, 10, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		due, next := expr.CatchUp(c.last, now)
+		assert.Equal(t, c.due, due, "last trigger %s", c.last)
+		assert.Equal(t, tomorrow, next)
+	}
+	assert.Equal(t, time.Date(2026, time.October, 11, 0, 0, 0, 0, time.UTC), MissedElapse(expr, cases[3].last, now))
+	assert.True(t, MissedElapse(expr, time.Time{}, now).IsZero())
+
+	due, next := MustParse("2019-*-* 00:00").CatchUp(now.AddDate(-10, 0, 0), now)
+	assert.True(t, due)
+	assert.True(t, next.IsZero())
+}
+
+func TestStateStore(t *testing.T) {
+	last := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	for _, store := range []StateStore{NewMemoryStateStore(), FileStateStore{Dir: t.TempDir()}} {
+		stored, err := store.LastTrigger("backup.timer")
+		require.NoError(t, err)
+		assert.True(t, stored.IsZero())
+
+		require.NoError(t, store.SetLastTrigger("backup.timer", last))
+		require.NoError(t, store.SetLastTrigger("other.timer", last.Add(time.Hour)))
+		stored, err = store.LastTrigger("backup.timer")
+		require.NoError(t, err)
+		assert.True(t, last.Equal(stored), "%T: expected %s, got %s", store, last, stored)
+	}
+
+	_, err := FileStateStore{Dir: t.TempDir()}.LastTrigger("../backup.timer")
+	assert.Error(t, err)
+	assert.Error(t, FileStateStore{Dir: filepath.Join(t.TempDir(), "missing")}.SetLastTrigger("backup.timer", last))
+}
+
+/******************************************************************************/
+
+func TestFixedJitter(t *testing.T) {
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	delay := FixedDelay(time.Hour, seed, "backup.timer")
+	assert.Equal(t, delay, FixedDelay(time.Hour, seed, "backup.timer"))
+	assert.Equal(t, 26*time.Minute+54342088942*time.Nanosecond, delay)
+	assert.NotEqual(t, delay, FixedDelay(time.Hour, seed, "cleanup.timer"))
+	assert.NotEqual(t, delay, FixedDelay(time.Hour, []byte("fedcba9876543210fedcba9876543210"), "backup.timer"))
+	assert.Zero(t, FixedDelay(0, seed, "backup.timer"))
+
+	jitter := NewFixedJitter(MustParse("daily"), time.Hour, seed, "backup.timer")
+	midnight := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	from := midnight.Add(-time.Hour)
+	next := jitter.Next(from)
+	assert.Equal(t, midnight.Add(delay), next)
+	// the delayed elapse is still ahead after the elapse itself
+	assert.Equal(t, next, jitter.Next(midnight.Add(time.Second)))
+	assert.Equal(t, next.AddDate(0, 0, 1), jitter.Next(next))
+	assert.True(t, NewFixedJitter(MustParse("2019-*-* 00:00"), time.Hour, seed, "").Next(from).IsZero())
+}
+
+func TestJitter(t *testing.T) {
+	jitter := NewJitter(MustParse("daily"), time.Hour)
+	midnight := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		next := jitter.Next(midnight.Add(-time.Minute))
+		delay := next.Sub(midnight)
+		assert.True(t, delay >= 0 && delay < time.Hour, "delay %s", delay)
+		delays[delay] = true
+	}
+	assert.Greater(t, len(delays), 1)
+	assert.Equal(t, midnight, NewJitter(MustParse("daily"), 0).Next(midnight.Add(-time.Minute)))
+}
+
+func TestMachineID(t *testing.T) {
+	dir := t.TempDir()
+	defer func(saved []string) { machineIDFiles = saved }(machineIDFiles)
+	machineIDFiles = []string{filepath.Join(dir, "missing"), filepath.Join(dir, "empty"), filepath.Join(dir, "machine-id")}
+	require.NoError(t, os.WriteFile(machineIDFiles[1], []byte("\n"), 0o644))
+	_, err := MachineID()
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(machineIDFiles[2], []byte("0123456789abcdef0123456789abcdef\n"), 0o644))
+	id, err := MachineID()
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", string(id))
+}
+
+func TestPerturbation(t *testing.T) {
+	bootID := []byte("5b4e8ad6-3c0e-4c2b-9f4e-1f7a0e8d2c61")
+	assert.Equal(t, 17939908*time.Microsecond, Perturbation(bootID))
+	assert.Equal(t, Perturbation(bootID), Perturbation([]byte("5b4e8ad63c0e4c2b9f4e1f7a0e8d2c61")))
+	assert.Equal(t, Perturbation([]byte("seed")), Perturbation([]byte("seed")))
+	assert.Less(t, Perturbation([]byte("seed")), time.Minute)
+
+	defer func(saved string) { bootIDFile = saved }(bootIDFile)
+	bootIDFile = filepath.Join(t.TempDir(), "boot_id")
+	_, err := BootID()
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(bootIDFile, append(bootID, '\n'), 0o644))
+	id, err := BootID()
+	require.NoError(t, err)
+	assert.Equal(t, bootID, id)
+}
+
+/******************************************************************************/
+
+func TestCoalescer(t *testing.T) {
+	exprs := []*Expression{MustParse("12:00"), MustParse("12:00:40"), MustParse("12:05"), MustParse("2019-*-* 00:00")}
+	from := time.Date(2026, time.October, 18, 11, 58, 0, 0, time.UTC)
+	at := func(hour, min, sec int) time.Time {
+		return time.Date(2026, time.October, 18, hour, min, sec, 0, time.UTC)
+	}
+
+	cases := []struct {
+		coalescer Coalescer
+		wakeup    time.Time
+		due       []int
+	}{
+		// at the perturbation of the minute
+		{Coalescer{Accuracy: time.Minute, Perturbation: 17 * time.Second}, at(12, 0, 17), []int{0}},
+		{Coalescer{Accuracy: time.Minute, Perturbation: 50 * time.Second}, at(12, 0, 50), []int{0, 1}},
+		// at the perturbation of 10s, then of the second
+		{Coalescer{Accuracy: 5 * time.Second, Perturbation: 42 * time.Second}, at(12, 0, 2), []int{0}},
+		{Coalescer{Accuracy: 500 * time.Millisecond, Perturbation: 42 * time.Second}, at(12, 0, 0), []int{0}},
+		// as late as possible
+		{Coalescer{Accuracy: 10 * time.Minute, Perturbation: 17 * time.Second}, at(12, 9, 17), []int{0, 1, 2}},
+		{Coalescer{}, at(12, 0, 0), []int{0}},
+	}
+	for _, c := range cases {
+		wakeup, due := c.coalescer.Next(exprs, from)
+		assert.Equal(t, c.wakeup, wakeup, "%+v", c.coalescer)
+		assert.Equal(t, c.due, due, "%+v", c.coalescer)
+	}
+
+	wakeup, due := Coalescer{Accuracy: time.Minute}.Next(exprs[3:], from)
+	assert.True(t, wakeup.IsZero())
+	assert.Empty(t, due)
+}
+
+/******************************************************************************/
+
+func TestTimerUnit(t *testing.T) {
+	boot := time.Date(2026, time.October, 18, 8, 0, 0, 0, time.UTC)
+	var unit TimerUnit
+	require.NoError(t, unit.Set("OnCalendar", "daily"))
+	require.NoError(t, unit.Set("OnBootSec", "15min"))
+	require.NoError(t, unit.Set("OnUnitActiveSec", "1h"))
+	assert.EqualError(t, unit.Set("OnCalendar", "*-13-01"), "syntax error in month field: '13'")
+	assert.Error(t, unit.Set("OnBootSec", "soon"))
+	assert.EqualError(t, unit.Set("OnSundaySec", "1h"), "timer: unknown trigger setting 'OnSundaySec'")
+
+	cases := []struct {
+		name     string
+		state    TimerState
+		from     time.Time
+		expected time.Time
+	}{
+		{"after boot", TimerState{Boot: boot}, boot.Add(time.Minute), boot.Add(15 * time.Minute)},
+		{"overdue boot", TimerState{Boot: boot}, boot.Add(time.Hour), boot.Add(time.Hour)},
+		{"boot fired", TimerState{Boot: boot, LastTrigger: boot.Add(15 * time.Minute)}, boot.Add(20 * time.Minute), boot.Add(75 * time.Minute)},
+		{"unit active later", TimerState{Boot: boot, LastTrigger: boot.Add(15 * time.Minute), UnitActive: boot.Add(30 * time.Minute)}, boot.Add(40 * time.Minute), boot.Add(90 * time.Minute)},
+		{"calendar first", TimerState{Boot: boot, LastTrigger: boot.Add(15 * time.Hour)}, boot.Add(15*time.Hour + time.Minute), time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{"unknown boot", TimerState{}, boot, time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, unit.Next(c.state, c.from), c.name)
+	}
+
+	// empty values reset a setting
+	require.NoError(t, unit.Set("OnCalendar", ""))
+	require.NoError(t, unit.Set("OnUnitActiveSec", ""))
+	assert.Equal(t, []MonotonicTrigger{{Base: FromBoot, Offset: 15 * time.Minute}}, unit.Monotonic)
+	assert.True(t, unit.Next(TimerState{Boot: boot, LastTrigger: boot.Add(15 * time.Minute)}, boot.Add(time.Hour)).IsZero())
+
+	startup := TimerUnit{Monotonic: []MonotonicTrigger{{Base: FromStartup, Offset: time.Minute}, {Base: FromActivation, Offset: time.Hour}}}
+	assert.Equal(t, boot.Add(time.Minute), startup.Next(TimerState{Boot: boot}, boot))
+	assert.Equal(t, boot.Add(11*time.Minute), startup.Next(TimerState{Boot: boot, Startup: boot.Add(10 * time.Minute)}, boot))
+	assert.Equal(t, boot.Add(time.Hour+2*time.Minute), startup.Next(TimerState{Boot: boot, Activation: boot.Add(2 * time.Minute), LastTrigger: boot.Add(time.Minute)}, boot.Add(3*time.Minute)))
+}
+
+/******************************************************************************/
+
+func TestMetrics(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	m := &Metrics{Clock: NewFakeClock(now)}
+	var observer Observer = m
+	observer.Scheduled("backup", now.Add(90*time.Second))
+	observer.Started("backup", now.Add(-time.Hour), now.Add(-time.Hour+250*time.Millisecond))
+	observer.Missed("backup", now.Add(-2*time.Hour))
+	observer.Missed("backup", now.Add(-3*time.Hour))
+	observer.Scheduled(`say "hi"`, now.Add(-time.Second))
+	observer.Scheduled("done", time.Time{})
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `# HELP systemdexpr_timer_next_run_seconds Seconds until the next elapse of the timer.
+# TYPE systemdexpr_timer_next_run_seconds gauge
+systemdexpr_timer_next_run_seconds{timer="backup"} 90
+systemdexpr_timer_next_run_seconds{timer="say \"hi\""} 0
+# HELP systemdexpr_timer_last_run_timestamp_seconds Unix time of the start of the last run of the timer.
+# TYPE systemdexpr_timer_last_run_timestamp_seconds gauge
+systemdexpr_timer_last_run_timestamp_seconds{timer="backup"} 1792321200.25
+# HELP systemdexpr_timer_runs_total Runs of the timer.
+# TYPE systemdexpr_timer_runs_total counter
+systemdexpr_timer_runs_total{timer="backup"} 1
+systemdexpr_timer_runs_total{timer="done"} 0
+systemdexpr_timer_runs_total{timer="say \"hi\""} 0
+# HELP systemdexpr_timer_missed_elapses_total Elapses of the timer which were not run.
+# TYPE systemdexpr_timer_missed_elapses_total counter
+systemdexpr_timer_missed_elapses_total{timer="backup"} 2
+systemdexpr_timer_missed_elapses_total{timer="done"} 0
+systemdexpr_timer_missed_elapses_total{timer="say \"hi\""} 0
+`, string(body))
+}
+
+/******************************************************************************/
+
+func TestNext_Concurrent(t *testing.T) {
+	expr := MustParse("Mon..Fri *-*-01..07 09:00")
+	from := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	expected := expr.NextN(from, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, expected, expr.NextN(from, 10))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		expression string
+		options    Options
+		token      string
+		offset     int
+	}{
+		{"*-13-01", Options{}, "13", 2},
+		{"Mon *-*-* 25:00", Options{}, "25", 10},
+		{"12-02-30 10:61:00", Options{}, "61", 12},
+		{"*-*-01~40 10:00", Options{}, "40", 7},
+		{"Mon..Fry 09:00", Options{}, "Mon..Fry", 0},
+		{"a b c d e f", Options{}, "e f", 8},
+		{"Hourly", Options{NoAliases: true}, "Hourly", 0},
+		{"-", Options{}, "-", 0},
+		{"--", Options{}, "--", 0},
+		{"Mon -", Options{}, "-", 4},
+	}
+	for _, c := range cases {
+		_, err := ParseWithOptions(c.expression, c.options)
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr, c.expression)
+		assert.Equal(t, c.token, parseErr.Token, c.expression)
+		assert.Equal(t, c.offset, parseErr.Offset, c.expression)
+		assert.Equal(t, c.token, parseErr.Expression[parseErr.Offset:parseErr.Offset+len(parseErr.Token)])
+	}
+}
+
+func TestParseTimespan(t *testing.T) {
+	cases := map[string]time.Duration{
+		"2h 30min":  2*time.Hour + 30*time.Minute,
+		"2h30min":   2*time.Hour + 30*time.Minute,
+		"1.5d":      36 * time.Hour,
+		"500ms":     500 * time.Millisecond,
+		"90":        90 * time.Second,
+		"1y 12M":    2 * 31557600 * time.Second,
+		"3 weeks":   21 * 24 * time.Hour,
+		"5 min 10s": 5*time.Minute + 10*time.Second,
+	}
+	for s, expected := range cases {
+		d, err := ParseTimespan(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+	}
+	for _, s := range []string{"", "3 fortnights", "h", "1h-2m"} {
+		_, err := ParseTimespan(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	now := time.Date(2026, time.October, 16, 14, 30, 15, 0, berlin)
+
+	cases := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2026, time.October, 16, 0, 0, 0, 0, berlin)},
+		{"yesterday", time.Date(2026, time.October, 15, 0, 0, 0, 0, berlin)},
+		{"tomorrow UTC", time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)},
+		{"epoch", time.Unix(0, 0)},
+		{"@1700000000", time.Unix(1700000000, 0)},
+		{"@1700000000.25", time.Unix(1700000000, 250000000)},
+		{"+3h", now.Add(3 * time.Hour)},
+		{"-1d", now.Add(-24 * time.Hour)},
+		{"2h 30min ago", now.Add(-150 * time.Minute)},
+		{"10min left", now.Add(10 * time.Minute)},
+		{"2026-10-16 12:00", time.Date(2026, time.October, 16, 12, 0, 0, 0, berlin)},
+		{"Fri 2026-10-16 12:00:05.5", time.Date(2026, time.October, 16, 12, 0, 5, 500000000, berlin)},
+		{"2026-10-16", time.Date(2026, time.October, 16, 0, 0, 0, 0, berlin)},
+		{"11:12", time.Date(2026, time.October, 16, 11, 12, 0, 0, berlin)},
+		{"Thu 2026-10-15 08:00 UTC", time.Date(2026, time.October, 15, 8, 0, 0, 0, time.UTC)},
+		{"2026-10-16 12:00 America/New_York", time.Date(2026, time.October, 16, 16, 0, 0, 0, time.UTC)},
+		{"2026-10-16T12:00:00Z", time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)},
+		{"2026-10-16T12:00:00+02:00", time.Date(2026, time.October, 16, 10, 0, 0, 0, time.UTC)},
+		{"2026-10-16 12:00 -0530", time.Date(2026, time.October, 16, 17, 30, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		ts, err := ParseTimestamp(c.s, now)
+		require.NoError(t, err, c.s)
+		assert.True(t, c.expected.Equal(ts), "%s: expected %s, got %s", c.s, c.expected, ts)
+	}
+
+	for _, s := range []string{"", "Mon 2026-10-16", "2026-02-30", "25:00", "soon", "2026-10-16 12:00 Mars/Olympus", "@never", "+3 fortnights"} {
+		_, err := ParseTimestamp(s, now)
+		assert.Error(t, err, s)
+	}
+}
+
 func TestPeriodicConfig_DSTChange_Transitions(t *testing.T) {
 	locName := "America/Los_Angeles"
 	loc, err := time.LoadLocation(locName)
@@ -991,7 +2197,7 @@ func TestNext_DaylightSaving_Property_Brazil(t *testing.T) {
 
 // Issue: https://github.com/gorhill/cronexpr/issues/16
 func TestInterval_Interval60Issue(t *testing.T) {
-	_, err := Parse("*/60 * * * * *")
+	_, err := ParseCron("*/60 * * * * *")
 	if err == nil {
 		t.Errorf("parsing with interval 60 should return err")
 	}
@@ -0,0 +1,165 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"time"
+)
+
+/******************************************************************************/
+
+// A MonotonicBase is the instant from which a monotonic trigger counts.
+type MonotonicBase uint8
+
+const (
+	// FromActivation counts from the activation of the timer, `OnActiveSec=`.
+	FromActivation MonotonicBase = iota
+	// FromBoot counts from the boot of the machine, `OnBootSec=`.
+	FromBoot
+	// FromStartup counts from the start of the service manager,
+	// `OnStartupSec=`.
+	FromStartup
+	// FromUnitActive counts from the last activation of the unit the timer
+	// activates, `OnUnitActiveSec=`.
+	FromUnitActive
+	// FromUnitInactive counts from the last deactivation of the unit the
+	// timer activates, `OnUnitInactiveSec=`.
+	FromUnitInactive
+)
+
+// Settings of the monotonic triggers, by base
+var monotonicSettings = []string{"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec"}
+
+// A MonotonicTrigger elapses some time after an instant, such as
+// `OnBootSec=15min`.
+type MonotonicTrigger struct {
+	Base   MonotonicBase
+	Offset time.Duration
+}
+
+// TimerState holds the instants monotonic triggers count from. An instant
+// which is not known is left zero, the triggers counting from it are then
+// ignored.
+type TimerState struct {
+	Boot         time.Time
+	Startup      time.Time // Boot if zero
+	Activation   time.Time
+	UnitActive   time.Time
+	UnitInactive time.Time
+	LastTrigger  time.Time
+}
+
+// A TimerUnit is the set of triggers of a systemd timer unit.
+type TimerUnit struct {
+	Calendar  []*Expression
+	Monotonic []MonotonicTrigger
+}
+
+/******************************************************************************/
+
+// Set adds the trigger of a setting of the `[Timer]` section, such as
+// `OnCalendar` or `OnBootSec`. As in a unit file, an empty value drops the
+// triggers of the setting set before it.
+func (u *TimerUnit) Set(key, value string) error {
+	if key == "OnCalendar" {
+		if value == "" {
+			u.Calendar = nil
+			return nil
+		}
+		expr, err := ParseWithOptions(value, Options{MatchBothDays: true})
+		if err != nil {
+			return err
+		}
+		u.Calendar = append(u.Calendar, expr)
+		return nil
+	}
+
+	for base, setting := range monotonicSettings {
+		if key != setting {
+			continue
+		}
+		if value == "" {
+			kept := u.Monotonic[:0]
+			for _, trigger := range u.Monotonic {
+				if trigger.Base != MonotonicBase(base) {
+					kept = append(kept, trigger)
+				}
+			}
+			u.Monotonic = kept
+			return nil
+		}
+		offset, err := ParseTimespan(value)
+		if err != nil {
+			return err
+		}
+		u.Monotonic = append(u.Monotonic, MonotonicTrigger{Base: MonotonicBase(base), Offset: offset})
+		return nil
+	}
+	return fmt.Errorf("timer: unknown trigger setting '%s'", key)
+}
+
+// Next returns when the timer elapses next, its triggers being evaluated
+// from `fromTime` as systemd does:
+//   - triggers counting from the boot, the startup or the activation fire
+//     once: they are ignored once past if the timer already triggered,
+//   - triggers counting from the unit count from the last trigger if it is
+//     later.
+//
+// An overdue monotonic trigger elapses at `fromTime`. The zero time is
+// returned if the timer does not elapse anymore. Suspended time is not
+// accounted for, unlike with systemd's `CLOCK_MONOTONIC`.
+func (u *TimerUnit) Next(state TimerState, fromTime time.Time) time.Time {
+	var next time.Time
+	earliest := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	for _, expr := range u.Calendar {
+		earliest(expr.Next(fromTime))
+	}
+	for _, trigger := range u.Monotonic {
+		base := state.base(trigger.Base)
+		if base.IsZero() {
+			continue
+		}
+		elapse := base.Add(trigger.Offset)
+		if elapse.Before(fromTime) {
+			oneShot := trigger.Base == FromActivation || trigger.Base == FromBoot || trigger.Base == FromStartup
+			if oneShot && !state.LastTrigger.IsZero() {
+				continue
+			}
+			elapse = fromTime
+		}
+		earliest(elapse)
+	}
+	return next
+}
+
+// base returns the instant a trigger counts from, the zero time if unknown.
+func (s TimerState) base(base MonotonicBase) time.Time {
+	latest := func(a, b time.Time) time.Time {
+		if a.IsZero() || b.After(a) {
+			return b
+		}
+		return a
+	}
+	switch base {
+	case FromActivation:
+		return s.Activation
+	case FromBoot:
+		return s.Boot
+	case FromStartup:
+		if s.Startup.IsZero() {
+			return s.Boot
+		}
+		return s.Startup
+	case FromUnitActive:
+		return latest(s.UnitActive, s.LastTrigger)
+	case FromUnitInactive:
+		return latest(s.UnitInactive, s.LastTrigger)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,252 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/******************************************************************************/
+
+// Time span units of systemd.time(7)
+var timespanUnits = map[string]time.Duration{
+	"usec": time.Microsecond, "us": time.Microsecond, "µs": time.Microsecond, "μs": time.Microsecond,
+	"msec": time.Millisecond, "ms": time.Millisecond,
+	"seconds": time.Second, "second": time.Second, "sec": time.Second, "s": time.Second, "": time.Second,
+	"minutes": time.Minute, "minute": time.Minute, "min": time.Minute, "m": time.Minute,
+	"hours": time.Hour, "hour": time.Hour, "hr": time.Hour, "h": time.Hour,
+	"days": 24 * time.Hour, "day": 24 * time.Hour, "d": 24 * time.Hour,
+	"weeks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	"months": 2629800 * time.Second, "month": 2629800 * time.Second, "M": 2629800 * time.Second,
+	"years": 31557600 * time.Second, "year": 31557600 * time.Second, "y": 31557600 * time.Second,
+}
+
+var timespanFinder = regexp.MustCompile(`^\s*(\d+(?:\.\d*)?|\.\d+)\s*([a-zA-Zµμ]*)`)
+
+var timestampWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var (
+	timestampISOFinder    = regexp.MustCompile(`^(\d{4}-\d{1,2}-\d{1,2})T`)
+	timestampDateFinder   = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	timestampTimeFinder   = regexp.MustCompile(`^(\d{1,2}):(\d{2})(?::(\d{2})(\.\d+)?)?$`)
+	timestampOffsetFinder = regexp.MustCompile(`^(.*?)(Z|[+-]\d{2}(?::?\d{2})?)$`)
+)
+
+/******************************************************************************/
+
+// ParseTimespan reads a time span as defined by systemd.time(7), such as
+// `2h 30min`, `1.5d` or `500ms`. A number without a unit is in seconds.
+func ParseTimespan(s string) (time.Duration, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, fmt.Errorf("timespan: empty time span")
+	}
+	var d time.Duration
+	for rest != "" {
+		m := timespanFinder.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("timespan: invalid time span '%s'", s)
+		}
+		unit, ok := timespanUnits[m[2]]
+		if !ok {
+			return 0, fmt.Errorf("timespan: unknown unit '%s'", m[2])
+		}
+		value, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("timespan: invalid time span '%s'", s)
+		}
+		d += time.Duration(value * float64(unit))
+		rest = strings.TrimSpace(rest[len(m[0]):])
+	}
+	return d, nil
+}
+
+/******************************************************************************/
+
+// ParseTimestamp reads a timestamp as defined by systemd.time(7), relative
+// times being computed from `now`:
+//
+//	now, today, yesterday, tomorrow, epoch
+//	@1700000000          seconds since the epoch
+//	+3h, -1d, 2h ago     time spans from now, see ParseTimespan
+//	Fri 2026-10-16 12:00:00.5 Europe/Berlin
+//	2026-10-16T12:00:00+02:00
+//
+// The weekday, the time and the time zone are optional, the weekday must
+// match the date when given. Without a date, today is meant. Without a time
+// zone, the location of `now` is used.
+func ParseTimestamp(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return time.Time{}, fmt.Errorf("timestamp: empty timestamp")
+	case s == "now":
+		return now, nil
+	case s[0] == '@':
+		secondStr, fraction, _ := strings.Cut(s[1:], ".")
+		seconds, err := strconv.ParseInt(secondStr, 10, 64)
+		nsec, ok := parseNanoseconds(fraction)
+		if err != nil || !ok {
+			return time.Time{}, fmt.Errorf("timestamp: invalid epoch time '%s'", s)
+		}
+		return time.Unix(seconds, int64(nsec)).In(now.Location()), nil
+	case s[0] == '+' || s[0] == '-':
+		d, err := ParseTimespan(s[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp: %s", err)
+		}
+		if s[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	case strings.HasSuffix(s, " ago") || strings.HasSuffix(s, " left"):
+		span, suffix := s[:strings.LastIndexByte(s, ' ')], s[strings.LastIndexByte(s, ' ')+1:]
+		d, err := ParseTimespan(span)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp: %s", err)
+		}
+		if suffix == "ago" {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+
+	fields := strings.Fields(timestampISOFinder.ReplaceAllString(s, "$1 "))
+	loc := now.Location()
+	if len(fields) > 1 {
+		if zone, err := parseTimestampZone(fields[len(fields)-1]); err == nil {
+			loc = zone
+			fields = fields[:len(fields)-1]
+		}
+	}
+	// `12:00:00Z` and `12:00:00+02:00` carry their zone
+	if m := timestampOffsetFinder.FindStringSubmatch(fields[len(fields)-1]); m != nil && strings.Contains(m[1], ":") {
+		zone, err := parseTimestampZone(m[2])
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = zone
+		fields[len(fields)-1] = m[1]
+	}
+	now = now.In(loc)
+
+	weekday := time.Weekday(-1)
+	if wd, ok := timestampWeekdays[strings.ToLower(fields[0])]; ok {
+		weekday = wd
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, fmt.Errorf("timestamp: invalid timestamp '%s'", s)
+	}
+
+	year, month, day := now.Date()
+	switch fields[0] {
+	case "today":
+		fields = fields[1:]
+	case "yesterday":
+		year, month, day = now.AddDate(0, 0, -1).Date()
+		fields = fields[1:]
+	case "tomorrow":
+		year, month, day = now.AddDate(0, 0, 1).Date()
+		fields = fields[1:]
+	case "epoch":
+		year, month, day = 1970, time.January, 1
+		loc = time.UTC
+		fields = fields[1:]
+	default:
+		if m := timestampDateFinder.FindStringSubmatch(fields[0]); m != nil {
+			year, _ = strconv.Atoi(m[1])
+			monthValue, _ := strconv.Atoi(m[2])
+			month = time.Month(monthValue)
+			day, _ = strconv.Atoi(m[3])
+			if month < time.January || month > time.December || day < 1 || day > time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+				return time.Time{}, fmt.Errorf("timestamp: invalid date '%s'", fields[0])
+			}
+			fields = fields[1:]
+		}
+	}
+
+	hour, minute, second, nsec := 0, 0, 0, 0
+	if len(fields) == 1 {
+		m := timestampTimeFinder.FindStringSubmatch(fields[0])
+		if m == nil {
+			return time.Time{}, fmt.Errorf("timestamp: invalid timestamp '%s'", s)
+		}
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			second, _ = strconv.Atoi(m[3])
+		}
+		nsec, _ = parseNanoseconds(strings.TrimPrefix(m[4], "."))
+		if hour > 23 || minute > 59 || second > 59 {
+			return time.Time{}, fmt.Errorf("timestamp: invalid time '%s'", fields[0])
+		}
+	} else if len(fields) > 1 {
+		return time.Time{}, fmt.Errorf("timestamp: invalid timestamp '%s'", s)
+	}
+
+	t := time.Date(year, month, day, hour, minute, second, nsec, loc)
+	if weekday >= 0 && t.Weekday() != weekday {
+		return time.Time{}, fmt.Errorf("timestamp: %s is a %s", t.Format("2006-01-02"), t.Weekday())
+	}
+	return t, nil
+}
+
+// parseNanoseconds reads the digits following the decimal point of a number
+// of seconds. Unlike a float, it does not round them.
+func parseNanoseconds(fraction string) (int, bool) {
+	if fraction == "" {
+		return 0, true
+	}
+	if len(fraction) > 9 {
+		fraction = fraction[:9]
+	}
+	nsec, err := strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+	return nsec, err == nil && nsec >= 0
+}
+
+// parseTimestampZone reads `UTC`, `Z`, a numeric offset such as `+02:00` or
+// the name of a time zone of the database.
+func parseTimestampZone(s string) (*time.Location, error) {
+	switch {
+	case s == "Z" || s == "UTC":
+		return time.UTC, nil
+	case s[0] == '+' || s[0] == '-':
+		digits := strings.Replace(s[1:], ":", "", 1)
+		if len(digits) != 2 && len(digits) != 4 {
+			return nil, fmt.Errorf("timestamp: invalid offset '%s'", s)
+		}
+		hours, err := strconv.Atoi(digits[:2])
+		minutes := 0
+		if err == nil && len(digits) == 4 {
+			minutes, err = strconv.Atoi(digits[2:])
+		}
+		if err != nil || hours > 23 || minutes > 59 {
+			return nil, fmt.Errorf("timestamp: invalid offset '%s'", s)
+		}
+		offset := hours*3600 + minutes*60
+		if s[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone(s, offset), nil
+	case strings.ContainsAny(s, "0123456789:.") && !strings.Contains(s, "/"):
+		return nil, fmt.Errorf("timestamp: invalid time zone '%s'", s)
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp: unknown time zone '%s'", s)
+	}
+	return loc, nil
+}
@@ -0,0 +1,118 @@
+package systemdexpr
+
+/******************************************************************************/
+
+import (
+	"context"
+	"time"
+)
+
+/******************************************************************************/
+
+const (
+	// DefaultMaxWait is how long a Waiter sleeps at most at once when its
+	// MaxWait is zero.
+	DefaultMaxWait = time.Minute
+	// DefaultJumpTolerance is how far the wall clock of a Waiter may drift
+	// from the monotonic clock when its Tolerance is zero.
+	DefaultJumpTolerance = time.Second
+)
+
+// A WaitResult tells how a wait of a Waiter ended.
+type WaitResult uint8
+
+const (
+	// WaitElapsed means the wall clock reached the instant waited for.
+	WaitElapsed WaitResult = iota
+	// WaitJumped means the wall clock jumped, by a step or a suspend of the
+	// host, and has not reached the instant waited for.
+	WaitJumped
+	// WaitCanceled means the context was canceled.
+	WaitCanceled
+)
+
+// A Waiter sleeps until instants of the wall clock. A timer runs on the
+// monotonic clock, which does not follow steps of the wall clock nor count
+// the time the host is suspended, so a Waiter sleeps in chunks and compares
+// both clocks after each of them, like systemd re-checks its timers on
+// `TIME_CHANGE` events. The zero value gives sensible defaults.
+type Waiter struct {
+	// Clock tells the time, RealClock when nil.
+	Clock Clock
+	// MaxWait bounds each chunk of sleep: a jump of the wall clock is noticed
+	// within it. DefaultMaxWait when zero.
+	MaxWait time.Duration
+	// Tolerance is how far the wall clock may drift from the monotonic clock
+	// during a chunk before it is deemed to have jumped.
+	// DefaultJumpTolerance when zero.
+	Tolerance time.Duration
+}
+
+// Wait sleeps until the wall clock reaches `until`, the wall clock jumps or
+// the context is canceled.
+func (w Waiter) Wait(ctx context.Context, until time.Time) WaitResult {
+	clock := w.clock()
+	maxWait := w.MaxWait
+	if maxWait <= 0 {
+		maxWait = DefaultMaxWait
+	}
+	tolerance := w.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultJumpTolerance
+	}
+
+	for {
+		// Round(0) strips the monotonic reading of the real clock
+		start, startMono := clock.Now().Round(0), clock.Monotonic()
+		d := until.Sub(start)
+		if d <= 0 {
+			return WaitElapsed
+		}
+		if d > maxWait {
+			d = maxWait
+		}
+		timer := clock.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return WaitCanceled
+		case <-timer.C():
+		}
+
+		end, endMono := clock.Now().Round(0), clock.Monotonic()
+		drift := end.Sub(start) - (endMono - startMono)
+		if drift > tolerance || drift < -tolerance {
+			if !end.Before(until) {
+				return WaitElapsed
+			}
+			return WaitJumped
+		}
+	}
+}
+
+// WaitNext sleeps until the first elapse of a schedule following `fromTime`,
+// such as the Next method of an Expression, and returns it. The elapse is
+// recomputed from the current time whenever the wall clock jumps: elapses
+// skipped by a forward jump are not waited for, and a backward jump brings
+// earlier elapses back. The zero time is returned if the schedule does not
+// elapse anymore, and the error of the context if it is canceled.
+func (w Waiter) WaitNext(ctx context.Context, next func(time.Time) time.Time, fromTime time.Time) (time.Time, error) {
+	elapse := next(fromTime)
+	for !elapse.IsZero() {
+		switch w.Wait(ctx, elapse) {
+		case WaitElapsed:
+			return elapse, nil
+		case WaitCanceled:
+			return time.Time{}, ctx.Err()
+		}
+		elapse = next(w.clock().Now())
+	}
+	return elapse, nil
+}
+
+func (w Waiter) clock() Clock {
+	if w.Clock == nil {
+		return RealClock
+	}
+	return w.Clock
+}
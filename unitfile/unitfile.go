@@ -0,0 +1,166 @@
+// Package unitfile reads systemd unit files along with their drop-in
+// overrides, as described in systemd.unit(5).
+package unitfile
+
+/******************************************************************************/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/******************************************************************************/
+
+// An Entry is a `Key=Value` assignment of a unit file.
+type Entry struct {
+	File    string
+	Line    int
+	Section string
+	Key     string
+	Value   string
+}
+
+// A SyntaxError is a line of a unit file which cannot be read.
+type SyntaxError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+/******************************************************************************/
+
+// Parse reads the assignments of a unit file, `file` being used to report
+// where they come from. Comments are skipped and lines ending with a
+// backslash are continued on the next line.
+func Parse(r io.Reader, file string) ([]Entry, error) {
+	var entries []Entry
+	section := ""
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		entryLine := lineNo
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		for strings.HasSuffix(line, `\`) && scanner.Scan() {
+			lineNo++
+			next := strings.TrimSpace(scanner.Text())
+			// comments are ignored within continued lines
+			if next != "" && (next[0] == '#' || next[0] == ';') {
+				next = `\`
+			}
+			line = strings.TrimSuffix(line, `\`) + " " + next
+		}
+		line = strings.TrimSpace(strings.TrimSuffix(line, `\`))
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(line) < 3 {
+				return entries, &SyntaxError{File: file, Line: entryLine, Msg: fmt.Sprintf("invalid section header '%s'", line)}
+			}
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return entries, &SyntaxError{File: file, Line: entryLine, Msg: fmt.Sprintf("missing '=' in '%s'", line)}
+		}
+		if section == "" {
+			return entries, &SyntaxError{File: file, Line: entryLine, Msg: fmt.Sprintf("assignment outside of section '%s'", key)}
+		}
+		entries = append(entries, Entry{
+			File:    file,
+			Line:    entryLine,
+			Section: section,
+			Key:     key,
+			Value:   strings.TrimSpace(value),
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// ParseFile is like Parse, but reads a file.
+func ParseFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, path)
+}
+
+/******************************************************************************/
+
+// A Unit is a unit file with its drop-in overrides applied in order.
+type Unit struct {
+	Path    string
+	DropIns []string
+	Entries []Entry
+}
+
+// Load reads a unit file followed by the drop-ins `<path>.d/*.conf` next to
+// it, in lexical order.
+func Load(path string) (*Unit, error) {
+	entries, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	unit := Unit{Path: path, Entries: entries}
+
+	dropIns, err := filepath.Glob(filepath.Join(path+".d", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dropIns)
+	for _, dropIn := range dropIns {
+		entries, err := ParseFile(dropIn)
+		if err != nil {
+			return nil, err
+		}
+		unit.DropIns = append(unit.DropIns, dropIn)
+		unit.Entries = append(unit.Entries, entries...)
+	}
+	return &unit, nil
+}
+
+// Values returns the assignments of a key in a section. As for systemd list
+// settings, an empty assignment drops the values assigned before it and is
+// not returned.
+func (u *Unit) Values(section, key string) []Entry {
+	var values []Entry
+	for _, entry := range u.Entries {
+		if entry.Section != section || entry.Key != key {
+			continue
+		}
+		if entry.Value == "" {
+			values = nil
+			continue
+		}
+		values = append(values, entry)
+	}
+	return values
+}
+
+// Has tells whether a key is assigned in a section, even to an empty value.
+func (u *Unit) Has(section, key string) bool {
+	for _, entry := range u.Entries {
+		if entry.Section == section && entry.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package unitfile
+
+/******************************************************************************/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/******************************************************************************/
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(`# comment
+[Unit]
+Description=Nightly \
+  backup
+
+; another comment
+[Timer]
+OnCalendar = daily
+OnCalendar=
+Persistent=true
+`), "backup.timer")
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{File: "backup.timer", Line: 3, Section: "Unit", Key: "Description", Value: "Nightly  backup"},
+		{File: "backup.timer", Line: 8, Section: "Timer", Key: "OnCalendar", Value: "daily"},
+		{File: "backup.timer", Line: 9, Section: "Timer", Key: "OnCalendar", Value: ""},
+		{File: "backup.timer", Line: 10, Section: "Timer", Key: "Persistent", Value: "true"},
+	}, entries)
+
+	// a lone backslash continues nothing
+	for _, content := range []string{"[Timer]\n\\", "[Timer]\n\\\n\n"} {
+		entries, err := Parse(strings.NewReader(content), "backup.timer")
+		assert.NoErrorf(t, err, "parsing %q", content)
+		assert.Emptyf(t, entries, "parsing %q", content)
+	}
+
+	errors := []struct {
+		content string
+		msg     string
+	}{
+		{"[Timer]\nOnCalendar daily\n", "backup.timer:2: missing '=' in 'OnCalendar daily'"},
+		{"OnCalendar=daily\n", "backup.timer:1: assignment outside of section 'OnCalendar'"},
+		{"[Timer\n", "backup.timer:1: invalid section header '[Timer'"},
+	}
+	for _, e := range errors {
+		_, err := Parse(strings.NewReader(e.content), "backup.timer")
+		assert.EqualError(t, err, e.msg)
+	}
+}
+
+/******************************************************************************/
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("backup.timer", "[Timer]\nOnCalendar=daily\nOnCalendar=weekly\n")
+	write("backup.timer.d/10-reset.conf", "[Timer]\nOnCalendar=\nOnCalendar=Mon 02:00\n")
+	write("backup.timer.d/20-more.conf", "[Timer]\nOnCalendar=Fri 02:00\n")
+	write("backup.timer.d/notes.txt", "[Timer]\nOnCalendar=ignored\n")
+
+	unit, err := Load(filepath.Join(dir, "backup.timer"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "backup.timer.d/10-reset.conf"),
+		filepath.Join(dir, "backup.timer.d/20-more.conf"),
+	}, unit.DropIns)
+
+	values := unit.Values("Timer", "OnCalendar")
+	require.Len(t, values, 2)
+	assert.Equal(t, "Mon 02:00", values[0].Value)
+	assert.Equal(t, 3, values[0].Line)
+	assert.Equal(t, filepath.Join(dir, "backup.timer.d/10-reset.conf"), values[0].File)
+	assert.Equal(t, "Fri 02:00", values[1].Value)
+
+	assert.True(t, unit.Has("Timer", "OnCalendar"))
+	assert.False(t, unit.Has("Timer", "OnBootSec"))
+
+	_, err = Load(filepath.Join(dir, "missing.timer"))
+	assert.Error(t, err)
+}